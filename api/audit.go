@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+// default range of the history endpoints
+const defaultHistoryRange = 24 * time.Hour
+
+// RegisterAudit adds the /audit handler returning the audited prices of a token:
+// /audit?token=<address>&from=<time>&to=<time>, the range defaults to the last 24 hours.
+func RegisterAudit(mux *http.ServeMux, log *zap.SugaredLogger, auditLog db.AuditLog) {
+	mux.HandleFunc("/audit", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		token := query.Get("token")
+		if token == "" {
+			writeError(w, http.StatusBadRequest, errors.New("missing token"))
+			return
+		}
+		to, err := parseTime(query.Get("to"), time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		from, err := parseTime(query.Get("from"), to.Add(-defaultHistoryRange))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		audits, err := auditLog.GetPriceAudits(r.Context(), token, from, to)
+		if err != nil {
+			log.Errorw("error when get price audits", "token", token, "from", from, "to", to, "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get price audits"))
+			return
+		}
+		if audits == nil {
+			audits = []common.PriceAudit{}
+		}
+		writeJSON(w, http.StatusOK, audits)
+	})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+const defaultDiscoveryLimit = 100
+const maxDiscoveryLimit = 1000
+
+// RegisterDiscoveries adds the /discoveries handler returning the newest discovered tokens:
+// /discoveries?before=<time>&before_address=<address>&limit=<n>, pass the firstSeenAt and the address
+// of the last token as before and before_address to get the next page.
+func RegisterDiscoveries(mux *http.ServeMux, log *zap.SugaredLogger, feed db.DiscoveryFeed) {
+	mux.HandleFunc("/discoveries", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		var cursor common.TokenDiscoveryCursor
+		if value := query.Get("before"); value != "" {
+			before, err := parseTime(value, time.Time{})
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			// without an address the page starts with the discoveries before the time
+			cursor = common.TokenDiscoveryCursor{FirstSeenAt: before, Address: strings.ToLower(query.Get("before_address"))}
+		}
+		limit := uint64(defaultDiscoveryLimit)
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || parsed == 0 || parsed > maxDiscoveryLimit {
+				writeError(w, http.StatusBadRequest, errors.New("limit must be between 1 and 1000"))
+				return
+			}
+			limit = parsed
+		}
+		discoveries, err := feed.GetTokenDiscoveries(r.Context(), common.ChainBase.String(), cursor, limit)
+		if err != nil {
+			log.Errorw("error when get token discoveries", "cursor", cursor, "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get discoveries"))
+			return
+		}
+		if discoveries == nil {
+			discoveries = []common.TokenDiscovery{}
+		}
+		writeJSON(w, http.StatusOK, discoveries)
+	})
+}
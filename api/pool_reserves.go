@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+// RegisterPoolReserves adds the /pool-reserves handler returning the reserve history of a registered pool:
+// /pool-reserves?pool=<address>&from=<time>&to=<time>, the range defaults to the last 24 hours.
+func RegisterPoolReserves(mux *http.ServeMux, log *zap.SugaredLogger, registry db.PoolRegistry) {
+	mux.HandleFunc("/pool-reserves", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		pool := query.Get("pool")
+		if pool == "" {
+			writeError(w, http.StatusBadRequest, errors.New("missing pool"))
+			return
+		}
+		to, err := parseTime(query.Get("to"), time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		from, err := parseTime(query.Get("from"), to.Add(-defaultHistoryRange))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		reserves, err := registry.GetPoolReserves(r.Context(), pool, from, to)
+		if err != nil {
+			log.Errorw("error when get pool reserves", "pool", pool, "from", from, "to", to, "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get pool reserves"))
+			return
+		}
+		if reserves == nil {
+			reserves = []common.PoolReserve{}
+		}
+		writeJSON(w, http.StatusOK, reserves)
+	})
+}
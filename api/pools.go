@@ -0,0 +1,35 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
+	"github.com/kv-base-hack/base-token-rate/workers"
+	"go.uber.org/zap"
+)
+
+// RegisterPools adds the /pools handler returning the qualifying pools of a token: /pools?token=<address>.
+func RegisterPools(mux *http.ServeMux, log *zap.SugaredLogger, store kv.Store, demand DemandRecorder) {
+	mux.HandleFunc("/pools", func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			writeError(w, http.StatusBadRequest, errors.New("missing token"))
+			return
+		}
+		demand.RecordDemand(token)
+		key := workers.TokenPoolsKey(token)
+		data, err := store.Get(r.Context(), key)
+		if errors.Is(err, kv.ErrNotFound) {
+			writeError(w, http.StatusNotFound, errors.New("no pools for token"))
+			return
+		}
+		if err != nil {
+			log.Errorw("error when get key", "key", key, "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get pools"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
+	"github.com/kv-base-hack/base-token-rate/workers"
+	"go.uber.org/zap"
+)
+
+// RegisterPrices adds the /prices handler returning the published rate snapshot, /prices?token=<address>
+// returns the tokens of the snapshot with the address. The snapshot is served from the kv store so the
+// memory and bolt backends, which nothing else can read, are served too.
+func RegisterPrices(mux *http.ServeMux, log *zap.SugaredLogger, store kv.Store, demand DemandRecorder) {
+	mux.HandleFunc("/prices", func(w http.ResponseWriter, r *http.Request) {
+		data, err := store.Get(r.Context(), workers.RatePricesKey)
+		if errors.Is(err, kv.ErrNotFound) {
+			writeError(w, http.StatusNotFound, errors.New("no prices published yet"))
+			return
+		}
+		if err != nil {
+			log.Errorw("error when get key", "key", workers.RatePricesKey, "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get prices"))
+			return
+		}
+		token := strings.ToLower(r.URL.Query().Get("token"))
+		if token == "" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(data)
+			return
+		}
+		demand.RecordDemand(token)
+		var snapshot []common.Token
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			log.Errorw("error when unmarshal rate snapshot", "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get prices"))
+			return
+		}
+		// a token has a cex and a dex price when both are published
+		tokens := []common.Token{}
+		for _, t := range snapshot {
+			if strings.ToLower(t.Address) == token {
+				tokens = append(tokens, t)
+			}
+		}
+		if len(tokens) == 0 {
+			writeError(w, http.StatusNotFound, errors.New("no price for token"))
+			return
+		}
+		writeJSON(w, http.StatusOK, tokens)
+	})
+}
+
+type tokenInfoResponse struct {
+	CoinMarketCap *common.RedisTokenMetadata `json:"coinmarketcap,omitempty"`
+	CoinGecko     *common.CoinGeckoCoin      `json:"coingecko,omitempty"`
+}
+
+// RegisterTokenInfo adds the /token-info handler returning the coinmarketcap listing,
+// /token-info?address=<address>&chain=<chainId> returns the coinmarketcap and coingecko info of a token,
+// the chain defaults to base.
+func RegisterTokenInfo(mux *http.ServeMux, log *zap.SugaredLogger, store kv.Store) {
+	mux.HandleFunc("/token-info", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		address := strings.ToLower(query.Get("address"))
+		if address == "" {
+			data, err := store.Get(r.Context(), workers.CmcTokenInfoKey)
+			if errors.Is(err, kv.ErrNotFound) {
+				writeError(w, http.StatusNotFound, errors.New("no token info published yet"))
+				return
+			}
+			if err != nil {
+				log.Errorw("error when get key", "key", workers.CmcTokenInfoKey, "err", err)
+				writeError(w, http.StatusInternalServerError, errors.New("failed to get token info"))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(data)
+			return
+		}
+		chain := query.Get("chain")
+		if chain == "" {
+			chain = common.ChainBase.String()
+		}
+		var response tokenInfoResponse
+		var cmcIndex common.RedisTokenIndex
+		if err := getJSON(r.Context(), store, workers.CmcTokenIndexKey(chain), &cmcIndex); err != nil {
+			log.Errorw("error when get token index", "chain", chain, "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get token info"))
+			return
+		}
+		if m, exist := cmcIndex.Tokens[address]; exist {
+			response.CoinMarketCap = &m
+		}
+		var coingeckoIndex common.RedisCoingeckoIndex
+		if err := getJSON(r.Context(), store, workers.CoingeckoTokenIndexKey(chain), &coingeckoIndex); err != nil {
+			log.Errorw("error when get coingecko token index", "chain", chain, "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get token info"))
+			return
+		}
+		if coin, exist := coingeckoIndex.Tokens[address]; exist {
+			response.CoinGecko = &coin
+		}
+		if response.CoinMarketCap == nil && response.CoinGecko == nil {
+			writeError(w, http.StatusNotFound, errors.New("no info for token"))
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	})
+}
+
+// getJSON unmarshals the value of a key into v, a missing key leaves v unchanged.
+func getJSON(ctx context.Context, store kv.Store, key string, v interface{}) error {
+	data, err := store.Get(ctx, key)
+	if errors.Is(err, kv.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
+	"github.com/kv-base-hack/base-token-rate/workers"
+	"go.uber.org/zap"
+)
+
+// RegisterSearch adds the /search handler returning the ranked candidates of a symbol:
+// /search?symbol=<symbol>, the first candidate is the most trusted.
+func RegisterSearch(mux *http.ServeMux, log *zap.SugaredLogger, store kv.Store, identity *workers.IdentityResolver) {
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		symbol := r.URL.Query().Get("symbol")
+		if symbol == "" {
+			writeError(w, http.StatusBadRequest, errors.New("missing symbol"))
+			return
+		}
+		var snapshot []common.Token
+		data, err := store.Get(r.Context(), workers.RatePricesKey)
+		if err != nil && !errors.Is(err, kv.ErrNotFound) {
+			log.Errorw("error when get key", "key", workers.RatePricesKey, "err", err)
+			writeError(w, http.StatusInternalServerError, errors.New("failed to get tokens"))
+			return
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &snapshot); err != nil {
+				log.Errorw("error when unmarshal rate snapshot", "err", err)
+				writeError(w, http.StatusInternalServerError, errors.New("failed to get tokens"))
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, identity.Search(r.Context(), symbol, snapshot))
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/kv-base-hack/base-token-rate/lib/alert"
+	"github.com/kv-base-hack/base-token-rate/workers"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+const alertRulesFileFlag = "alert-rules-file"
+
+// NewAlertFlags creates new cli flags for price alerts.
+func NewAlertFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    alertRulesFileFlag,
+			Usage:   "json file with the alert rules and sinks, alerts are disabled if empty",
+			EnvVars: []string{"ALERT_RULES_FILE"},
+		},
+	}
+}
+
+// NewSnapshotObserversFromContext creates the observers of published snapshots from cli flags configuration.
+func NewSnapshotObserversFromContext(c *cli.Context, log *zap.SugaredLogger) ([]workers.SnapshotObserver, error) {
+	observers := []workers.SnapshotObserver{}
+	if path := c.String(alertRulesFileFlag); path != "" {
+		config, err := alert.LoadConfig(path)
+		if err != nil {
+			log.Errorw("error when load alert rules", "path", path, "err", err)
+			return nil, err
+		}
+		manager := alert.NewManager(log.With("worker", "alert"), config)
+		go manager.Run()
+		observers = append(observers, manager)
+	}
+	return observers, nil
+}
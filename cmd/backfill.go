@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"github.com/kv-base-hack/base-token-rate/workers"
+	"github.com/kv-base-hack/common/logger"
+	"github.com/urfave/cli/v2"
+)
+
+// NewBackfillCommand creates the command to rebuild the historical prices and candles from the trade logs.
+func NewBackfillCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "backfill",
+		Usage:  "rebuild historical token prices and candles from the trade logs of a block range",
+		Flags:  NewBackfillFlags(),
+		Action: backfill,
+	}
+}
+
+func backfill(c *cli.Context) error {
+	logger, flusher, err := logger.NewLogger(c)
+	if err != nil {
+		return err
+	}
+	defer flusher()
+	log := logger.Sugar()
+	database, err := NewDBFromContext(c)
+	if err != nil {
+		log.Errorw("error when connect to database", "err", err)
+		return err
+	}
+	defer database.Close()
+	pg := db.NewPostgres(database)
+
+	interval := c.Int64(backfillIntervalBlocksFlag)
+	if interval <= 0 {
+		return fmt.Errorf("invalid %s %d", backfillIntervalBlocksFlag, interval)
+	}
+	from := c.Int64(backfillFromBlockFlag)
+	to := c.Int64(backfillToBlockFlag)
+	if to == 0 {
+		to, err = pg.GetLastStoredBlock(c.Context, db.BaseTradeLogs)
+		if err != nil {
+			log.Errorw("error when get last stored block", "err", err)
+			return err
+		}
+	}
+	if from > to {
+		return fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if c.Bool(backfillResetFlag) {
+		name := workers.BackfillStateName(from-from%interval, interval)
+		if err := pg.DeleteWorkerState(c.Context, name); err != nil {
+			log.Errorw("error when delete backfill state", "name", name, "err", err)
+			return err
+		}
+	}
+	backfiller := workers.NewBackfiller(log, pg, pg, common.BaseAnchors, interval,
+		c.Int64(backfillChunkBlocksFlag), c.Int(backfillParallelismFlag))
+	backfiller.SetMetadataStore(pg)
+	return backfiller.Run(c.Context, from, to)
+}
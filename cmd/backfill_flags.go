@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	backfillFromBlockFlag      = "from-block"
+	backfillToBlockFlag        = "to-block"
+	backfillIntervalBlocksFlag = "interval-blocks"
+	backfillChunkBlocksFlag    = "chunk-blocks"
+	backfillParallelismFlag    = "parallelism"
+	backfillResetFlag          = "reset"
+)
+
+func NewBackfillFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Int64Flag{
+			Name:     backfillFromBlockFlag,
+			Usage:    "first block of the backfill",
+			Required: true,
+			EnvVars:  []string{"BACKFILL_FROM_BLOCK"},
+		},
+		&cli.Int64Flag{
+			Name:    backfillToBlockFlag,
+			Usage:   "last block of the backfill, the last stored block if not set",
+			EnvVars: []string{"BACKFILL_TO_BLOCK"},
+		},
+		&cli.Int64Flag{
+			Name:    backfillIntervalBlocksFlag,
+			Usage:   "blocks per candle, 1800 is an hour on base",
+			Value:   1800,
+			EnvVars: []string{"BACKFILL_INTERVAL_BLOCKS"},
+		},
+		&cli.Int64Flag{
+			Name:    backfillChunkBlocksFlag,
+			Usage:   "blocks per query and checkpoint, rounded down to whole candles",
+			Value:   18000,
+			EnvVars: []string{"BACKFILL_CHUNK_BLOCKS"},
+		},
+		&cli.IntFlag{
+			Name:    backfillParallelismFlag,
+			Usage:   "chunks processed in parallel",
+			Value:   4,
+			EnvVars: []string{"BACKFILL_PARALLELISM"},
+		},
+		&cli.BoolFlag{
+			Name:  backfillResetFlag,
+			Usage: "ignore the checkpoint of the range and start over",
+		},
+	}
+}
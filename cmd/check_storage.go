@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"github.com/kv-base-hack/base-token-rate/storage/db/dbtest"
+	"github.com/kv-base-hack/common/logger"
+	"github.com/urfave/cli/v2"
+)
+
+// NewCheckStorageCommand creates the command running the conformance suite against the storage backends.
+func NewCheckStorageCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "check-storage",
+		Usage:  "run the storage conformance suite against the memory and sqlite backends",
+		Action: checkStorage,
+	}
+}
+
+func checkStorage(c *cli.Context) error {
+	logger, flusher, err := logger.NewLogger(c)
+	if err != nil {
+		return err
+	}
+	defer flusher()
+	log := logger.Sugar()
+
+	dir, err := os.MkdirTemp("", "check-storage")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	sqliteFiles := 0
+	backends := map[string]func() (dbtest.Backend, error){
+		dbBackendMemory: func() (dbtest.Backend, error) {
+			return db.NewMemory(), nil
+		},
+		dbBackendSQLite: func() (dbtest.Backend, error) {
+			sqliteFiles++
+			return db.NewSQLite(filepath.Join(dir, fmt.Sprintf("check-%d.db", sqliteFiles)))
+		},
+	}
+
+	var failed error
+	for name, newBackend := range backends {
+		if err := dbtest.TestDB(c.Context, newBackend); err != nil {
+			log.Errorw("storage backend doesn't conform", "backend", name, "err", err)
+			failed = err
+			continue
+		}
+		log.Infow("storage backend conforms", "backend", name)
+	}
+	return failed
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	httpAddrFlag                 = "http-addr"
+	readinessMaxMissedCyclesFlag = "readiness-max-missed-cycles"
+)
+
+// NewHTTPFlags creates new cli flags for the http server.
+func NewHTTPFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    httpAddrFlag,
+			Usage:   "listen address of the http server serving /metrics, /healthz, /readyz, /status and the api",
+			Value:   ":8080",
+			EnvVars: []string{"HTTP_ADDR"},
+		},
+		&cli.IntFlag{
+			Name:    readinessMaxMissedCyclesFlag,
+			Usage:   "not ready when a worker didn't succeed within this many intervals",
+			Value:   3,
+			EnvVars: []string{"READINESS_MAX_MISSED_CYCLES"},
+		},
+	}
+}
+
+func newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+func serveHTTP(log *zap.SugaredLogger, addr string, handler http.Handler) {
+	log.Infow("start http server", "addr", addr)
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		log.Errorw("http server stopped", "err", err)
+	}
+}
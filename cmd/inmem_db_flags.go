@@ -1,6 +1,10 @@
 package main
 
 import (
+	"fmt"
+
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
+	"github.com/redis/go-redis/v9"
 	"github.com/urfave/cli/v2"
 )
 
@@ -9,6 +13,15 @@ const (
 	redisPortFlag     = "redis-port"
 	redisPasswordFlag = "redis-password"
 	redisDBFlag       = "redis-db"
+
+	kvBackendFlag = "kv-backend"
+	boltPathFlag  = "bolt-path"
+)
+
+const (
+	kvBackendRedis  = "redis"
+	kvBackendBolt   = "bolt"
+	kvBackendMemory = "memory"
 )
 
 // NewPostgreSQLFlags creates new cli flags for PostgreSQL client.
@@ -32,5 +45,41 @@ func NewRedisFlags() []cli.Flag {
 			Value:   0,
 			EnvVars: []string{"REDIS_DB"},
 		},
+		&cli.StringFlag{
+			Name:    kvBackendFlag,
+			Usage:   "store of the published snapshots: redis, bolt or memory, sharding and the discovery stream need redis",
+			Value:   kvBackendRedis,
+			EnvVars: []string{"KV_BACKEND"},
+		},
+		&cli.StringFlag{
+			Name:    boltPathFlag,
+			Usage:   "bbolt file of the bolt kv backend",
+			Value:   "base-token-rate.bolt",
+			EnvVars: []string{"BOLT_PATH"},
+		},
+	}
+}
+
+// NewRedisClientFromContext creates a redis client from cli flags configuration, it backs the redis kv
+// backend, sharding and the discovery stream.
+func NewRedisClientFromContext(c *cli.Context) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     c.String(redisHostFlag) + ":" + c.String(redisPortFlag),
+		Password: c.String(redisPasswordFlag),
+		DB:       c.Int(redisDBFlag),
+	})
+}
+
+// NewKVStoreFromContext opens the store of the published snapshots from cli flags configuration.
+func NewKVStoreFromContext(c *cli.Context, client *redis.Client) (kv.Store, error) {
+	switch backend := c.String(kvBackendFlag); backend {
+	case kvBackendRedis:
+		return kv.NewRedis(client), nil
+	case kvBackendBolt:
+		return kv.NewBolt(c.String(boltPathFlag))
+	case kvBackendMemory:
+		return kv.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown %s %q", kvBackendFlag, backend)
 	}
 }
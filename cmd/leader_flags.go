@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kv-base-hack/base-token-rate/lib/leader"
+	"github.com/kv-base-hack/base-token-rate/workers"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	leaderElectionFlag      = "leader-election"
+	leaderLockIDFlag        = "leader-lock-id"
+	leaderRetryIntervalFlag = "leader-retry-interval"
+)
+
+// NewLeaderFlags creates new cli flags for leader election between replicas.
+func NewLeaderFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    leaderElectionFlag,
+			Usage:   "only run the workers on the replica holding the leader lock",
+			EnvVars: []string{"LEADER_ELECTION"},
+		},
+		&cli.Int64Flag{
+			Name:    leaderLockIDFlag,
+			Usage:   "postgres advisory lock id used for leader election",
+			Value:   8453,
+			EnvVars: []string{"LEADER_LOCK_ID"},
+		},
+		&cli.DurationFlag{
+			Name:    leaderRetryIntervalFlag,
+			Usage:   "how often a standby tries to take the leader lock",
+			Value:   2 * time.Second,
+			EnvVars: []string{"LEADER_RETRY_INTERVAL"},
+		},
+	}
+}
+
+// NewLeadershipFromContext starts leader election if enabled, otherwise this replica is always leader.
+// The election takes a postgres lock, it fails without postgres.
+func NewLeadershipFromContext(c *cli.Context, log *zap.SugaredLogger, database *sqlx.DB) (workers.Leadership, error) {
+	if !c.Bool(leaderElectionFlag) {
+		return workers.AlwaysLeader{}, nil
+	}
+	if database == nil {
+		return nil, fmt.Errorf("--%s needs --%s %s", leaderElectionFlag, dbBackendFlag, dbBackendPostgres)
+	}
+	elector := leader.NewPostgresElector(log, database, c.Int64(leaderLockIDFlag), c.Duration(leaderRetryIntervalFlag))
+	go elector.Run()
+	return elector, nil
+}
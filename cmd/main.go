@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"sort"
 
 	"github.com/joho/godotenv"
 	obc "github.com/kv-base-hack/base-binance-client"
+	"github.com/kv-base-hack/base-token-rate/api"
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/breaker"
+	"github.com/kv-base-hack/base-token-rate/lib/cluster"
+	"github.com/kv-base-hack/base-token-rate/lib/erc20"
+	"github.com/kv-base-hack/base-token-rate/lib/health"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
+	"github.com/kv-base-hack/base-token-rate/lib/rateprovider"
+	"github.com/kv-base-hack/base-token-rate/lib/rateprovider/coingecko"
 	"github.com/kv-base-hack/base-token-rate/lib/rateprovider/dexscreener"
-	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"github.com/kv-base-hack/base-token-rate/lib/tracing"
 	"github.com/kv-base-hack/base-token-rate/workers"
-	inmem "github.com/kv-base-hack/common/inmem_db"
 	"github.com/kv-base-hack/common/logger"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
@@ -19,11 +29,22 @@ func main() {
 	_ = godotenv.Load()
 	app := cli.NewApp()
 	app.Action = run
+	app.Commands = []*cli.Command{
+		NewResetStateCommand(),
+		NewBackfillCommand(),
+		NewCheckStorageCommand(),
+	}
 	app.Flags = append(app.Flags, logger.NewSentryFlags()...)
 	app.Flags = append(app.Flags, NewPostgreSQLFlags()...)
+	app.Flags = append(app.Flags, NewStorageFlags()...)
 	app.Flags = append(app.Flags, NewRateFlags()...)
 	app.Flags = append(app.Flags, NewTokenInfoFlags()...)
 	app.Flags = append(app.Flags, NewRedisFlags()...)
+	app.Flags = append(app.Flags, NewLeaderFlags()...)
+	app.Flags = append(app.Flags, NewShardFlags()...)
+	app.Flags = append(app.Flags, NewHTTPFlags()...)
+	app.Flags = append(app.Flags, NewTracingFlags()...)
+	app.Flags = append(app.Flags, NewAlertFlags()...)
 	sort.Sort(cli.FlagsByName(app.Flags))
 
 	if err := app.Run(os.Args); err != nil {
@@ -39,26 +60,131 @@ func run(c *cli.Context) error {
 	zap.ReplaceGlobals(logger)
 	log := logger.Sugar()
 	log.Debugw("Starting application...")
-	database, err := NewDBFromContext(c)
+	shutdownTracing, err := tracing.Init(c.Context, c.String(tracingExporterFlag), c.String(otlpEndpointFlag))
+	if err != nil {
+		log.Errorw("error when init tracing", "err", err)
+		return err
+	}
+	defer func() {
+		_ = shutdownTracing(context.Background())
+	}()
+	mux := newServeMux()
+	go serveHTTP(log, c.String(httpAddrFlag), mux)
+	storage, err := NewStorageFromContext(c)
+	if err != nil {
+		log.Errorw("error when open storage", "backend", c.String(dbBackendFlag), "err", err)
+		return err
+	}
+	defer storage.Close()
+	// the audit log, discoveries, pools, risk and reorg tracking are postgres only
+	pg := storage.Postgres
+	if pg != nil {
+		api.RegisterAudit(mux, log, pg)
+		api.RegisterDiscoveries(mux, log, pg)
+		api.RegisterPoolReserves(mux, log, pg)
+	}
+	leadership, err := NewLeadershipFromContext(c, log, storage.SQL)
 	if err != nil {
-		log.Errorw("error when connect to database", "err", err)
+		log.Errorw("error when start leader election", "err", err)
 		return err
 	}
-	pg := db.NewPostgres(database)
 
-	redisHost := c.String(redisHostFlag)
-	redisPort := c.String(redisPortFlag)
-	redisPassword := c.String(redisPasswordFlag)
-	redisDB := c.Int(redisDBFlag)
-	redisAddr := redisHost + ":" + redisPort
-	redis := inmem.NewRedisClient(redisAddr, redisPassword, redisDB)
+	redisClient := NewRedisClientFromContext(c)
+	store, err := NewKVStoreFromContext(c, redisClient)
+	if err != nil {
+		log.Errorw("error when open kv store", "backend", c.String(kvBackendFlag), "err", err)
+		return err
+	}
+	defer store.Close()
+	useRedis := c.String(kvBackendFlag) == kvBackendRedis
+
+	tracker := health.NewTracker(c.Int(readinessMaxMissedCyclesFlag), leadership, store.Ping)
+	tracker.Register(mux)
+	scheduler := NewRefreshSchedulerFromContext(c)
+	api.RegisterPools(mux, log, store, scheduler)
+	api.RegisterPrices(mux, log, store, scheduler)
+	api.RegisterTokenInfo(mux, log, store)
+	identity := workers.NewIdentityResolver(log, store, c.Duration(identityRefreshFlag))
+	api.RegisterSearch(mux, log, store, identity)
 
 	tokenInfo := workers.NewTokenInfoWorker(log, c.Duration(tokenInfoWorkerDurationFlag),
-		c.String(cmcKeyFlag), c.String(cmcUrlFlag), redis)
+		c.String(cmcKeyFlag), c.String(cmcUrlFlag), store, leadership)
+	tokenInfo.SetStatusReporter(tracker)
+	tokenInfo.SetCreditBudget(c.Int(cmcCreditBudgetFlag))
+	if url := c.String(coingeckoUrlFlag); url != "" {
+		tokenInfo.SetCoinGecko(coingecko.NewCoinGecko(log, c.String(coingeckoKeyFlag), url))
+	}
+	tracker.RegisterWorker(workers.TokenInfoWorkerName, c.Duration(tokenInfoWorkerDurationFlag), true)
 	go tokenInfo.Run()
 
 	kaivestBinance := obc.NewKaivestBinanceClient(c.String(kaivestBinanceUrlFlag))
-	dexScreener := dexscreener.NewDexScreener(log, c.String(dexScreenerUrlFlag))
-	rateWorkerDuration := workers.NewRateWorker(log, c.Duration(rateWorkerDuration), dexScreener, redis, pg, kaivestBinance)
-	return rateWorkerDuration.Run()
+	dexScreenerBreaker := breaker.New(metrics.ProviderDexScreener, c.Int(breakerFailureThresholdFlag), c.Duration(breakerCooldownFlag))
+	tracker.RegisterBreaker(dexScreenerBreaker)
+	dexScreener := rateprovider.NewBreakerRateProvider(dexscreener.NewDexScreener(log, c.String(dexScreenerUrlFlag)), dexScreenerBreaker)
+
+	observers, err := NewSnapshotObserversFromContext(c, log)
+	if err != nil {
+		return err
+	}
+
+	sharding := c.Bool(shardingFlag)
+	if sharding && !useRedis {
+		return fmt.Errorf("sharding needs the %s kv backend", kvBackendRedis)
+	}
+	if sharding && !c.Bool(leaderElectionFlag) {
+		return fmt.Errorf("sharding needs --%s, the leader discovers the tokens and merges the shards", leaderElectionFlag)
+	}
+	var membership *cluster.Membership
+	if sharding {
+		// every replica refreshes its shard, only the leader merges them
+		membership = cluster.NewMembership(log, redisClient, c.String(replicaIDFlag),
+			c.Duration(shardHeartbeatIntervalFlag), c.Duration(shardMemberTTLFlag))
+		go membership.Run()
+		merger := workers.NewSnapshotMerger(log, c.Duration(shardMergeIntervalFlag), redisClient, store, membership, leadership)
+		merger.SetStatusReporter(tracker)
+		for _, o := range observers {
+			merger.AddObserver(o)
+		}
+		tracker.RegisterWorker(workers.SnapshotMergerName, c.Duration(shardMergeIntervalFlag), true)
+		go merger.Run()
+	}
+
+	rateWorker := workers.NewRateWorker(log, c.Duration(rateWorkerDuration), c.Duration(refreshHotIntervalFlag),
+		dexScreener, store, storage.DB, kaivestBinance, scheduler, leadership)
+	rateWorker.SetStatusReporter(tracker)
+	rateWorker.SetIdentityResolver(identity)
+	riskMaxLabel, err := RiskMaxLabelFromContext(c)
+	if err != nil {
+		log.Errorw("error when parse risk max label", "err", err)
+		return err
+	}
+	if pg != nil {
+		rateWorker.SetAuditLog(pg)
+		rateWorker.SetSupplyStore(pg, c.StringSlice(supplyLockerAddressesFlag))
+		var rpc *erc20.Client
+		if url := c.String(rpcUrlFlag); url != "" {
+			rpc = erc20.NewClient(url)
+		}
+		rateWorker.SetMetadataRegistry(pg, rpc)
+		if rpc != nil {
+			rateWorker.SetPoolRegistry(pg, rpc, common.BaseAnchors, c.StringSlice(poolFactoriesFlag))
+		}
+		var discoveryPublisher workers.DiscoveryPublisher
+		if useRedis {
+			discoveryPublisher = workers.NewRedisDiscoveryStream(redisClient)
+		}
+		rateWorker.SetDiscoveryFeed(pg, discoveryPublisher)
+		rateWorker.SetRiskStore(pg, riskMaxLabel)
+		rateWorker.SetReorgStore(pg, c.Int64(reorgWindowFlag), common.BaseAnchors)
+	}
+	if !sharding {
+		for _, o := range observers {
+			rateWorker.AddObserver(o)
+		}
+	}
+	tracker.RegisterWorker(workers.RateWorkerName, c.Duration(rateWorkerDuration), !sharding)
+	if sharding {
+		rateWorker.SetSharding(membership)
+	}
+	return rateWorker.Run()
 }
@@ -3,6 +3,8 @@ package main
 import (
 	"time"
 
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/workers"
 	"github.com/urfave/cli/v2"
 )
 
@@ -10,6 +12,21 @@ const (
 	dexScreenerUrlFlag    = "dex-screener"
 	rateWorkerDuration    = "rate-worker-duration"
 	kaivestBinanceUrlFlag = "kaivest-binance-url"
+
+	refreshHotIntervalFlag     = "refresh-hot-interval"
+	refreshWarmIntervalFlag    = "refresh-warm-interval"
+	refreshColdIntervalFlag    = "refresh-cold-interval"
+	refreshDormantIntervalFlag = "refresh-dormant-interval"
+	providerRequestBudgetFlag  = "provider-request-budget"
+
+	breakerFailureThresholdFlag = "breaker-failure-threshold"
+	breakerCooldownFlag         = "breaker-cooldown"
+
+	supplyLockerAddressesFlag = "supply-locker-addresses"
+	rpcUrlFlag                = "rpc-url"
+	poolFactoriesFlag         = "pool-factories"
+	riskMaxLabelFlag          = "risk-max-label"
+	reorgWindowFlag           = "reorg-window"
 )
 
 var rateFlags = []cli.Flag{
@@ -29,8 +46,96 @@ var rateFlags = []cli.Flag{
 		Usage:   "kaivest binance url",
 		EnvVars: []string{"KAIVEST_BINANCE_URL"},
 	},
+	&cli.DurationFlag{
+		Name:    refreshHotIntervalFlag,
+		Usage:   "refresh interval for the most active tokens, also the rate worker tick",
+		Value:   5 * time.Second,
+		EnvVars: []string{"REFRESH_HOT_INTERVAL"},
+	},
+	&cli.DurationFlag{
+		Name:    refreshWarmIntervalFlag,
+		Usage:   "refresh interval for moderately active tokens",
+		Value:   time.Minute,
+		EnvVars: []string{"REFRESH_WARM_INTERVAL"},
+	},
+	&cli.DurationFlag{
+		Name:    refreshColdIntervalFlag,
+		Usage:   "refresh interval for rarely traded tokens",
+		Value:   10 * time.Minute,
+		EnvVars: []string{"REFRESH_COLD_INTERVAL"},
+	},
+	&cli.DurationFlag{
+		Name:    refreshDormantIntervalFlag,
+		Usage:   "refresh interval for tokens without recent activity",
+		Value:   time.Hour,
+		EnvVars: []string{"REFRESH_DORMANT_INTERVAL"},
+	},
+	&cli.IntFlag{
+		Name:    providerRequestBudgetFlag,
+		Usage:   "max dex screener requests per minute",
+		Value:   250,
+		EnvVars: []string{"PROVIDER_REQUEST_BUDGET"},
+	},
+	&cli.IntFlag{
+		Name:    breakerFailureThresholdFlag,
+		Usage:   "consecutive dex screener failures before the circuit breaker opens",
+		Value:   5,
+		EnvVars: []string{"BREAKER_FAILURE_THRESHOLD"},
+	},
+	&cli.DurationFlag{
+		Name:    breakerCooldownFlag,
+		Usage:   "how long the circuit breaker stays open before a trial request",
+		Value:   30 * time.Second,
+		EnvVars: []string{"BREAKER_COOLDOWN"},
+	},
+	&cli.StringSliceFlag{
+		Name:    supplyLockerAddressesFlag,
+		Usage:   "addresses of token lockers, their balance isn't counted in the circulating supply",
+		EnvVars: []string{"SUPPLY_LOCKER_ADDRESSES"},
+	},
+	&cli.StringFlag{
+		Name:    rpcUrlFlag,
+		Usage:   "base json rpc url to read token name, symbol, decimals and total supply, the registry only has the transfer logs data if empty",
+		EnvVars: []string{"RPC_URL"},
+	},
+	&cli.StringSliceFlag{
+		Name:    poolFactoriesFlag,
+		Usage:   "factories of the registered pools, a pool is registered only if its factory returns it for its tokens",
+		Value:   cli.NewStringSlice(common.BaseFactories...),
+		EnvVars: []string{"POOL_FACTORIES"},
+	},
+	&cli.StringFlag{
+		Name:    riskMaxLabelFlag,
+		Usage:   "riskiest label of the published dex tokens: low, medium, high or honeypot, a token is held back until its risk is assessed, every token is published if empty",
+		EnvVars: []string{"RISK_MAX_LABEL"},
+	},
+	&cli.Int64Flag{
+		Name:    reorgWindowFlag,
+		Usage:   "blocks whose hashes are checked every cycle to detect a reorg, 0 disables it",
+		Value:   128,
+		EnvVars: []string{"REORG_WINDOW"},
+	},
 }
 
 func NewRateFlags() (flags []cli.Flag) {
 	return rateFlags
 }
+
+// NewRefreshSchedulerFromContext creates the dex refresh scheduler from cli flags configuration.
+func NewRefreshSchedulerFromContext(c *cli.Context) *workers.RefreshScheduler {
+	return workers.NewRefreshScheduler(workers.RefreshIntervals{
+		common.RefreshTierHot:     c.Duration(refreshHotIntervalFlag),
+		common.RefreshTierWarm:    c.Duration(refreshWarmIntervalFlag),
+		common.RefreshTierCold:    c.Duration(refreshColdIntervalFlag),
+		common.RefreshTierDormant: c.Duration(refreshDormantIntervalFlag),
+	}, c.Int(providerRequestBudgetFlag))
+}
+
+// RiskMaxLabelFromContext returns the riskiest published label, 0 if every token is published.
+func RiskMaxLabelFromContext(c *cli.Context) (common.RiskLabel, error) {
+	value := c.String(riskMaxLabelFlag)
+	if value == "" {
+		return 0, nil
+	}
+	return common.RiskLabelString(value)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/kv-base-hack/base-token-rate/workers"
+	"github.com/kv-base-hack/common/logger"
+	"github.com/urfave/cli/v2"
+)
+
+// NewResetStateCommand creates the command to delete the persisted rate worker state.
+func NewResetStateCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "reset-state",
+		Usage:  "delete persisted rate worker state, the next start rescans the last blocks",
+		Action: resetState,
+	}
+}
+
+func resetState(c *cli.Context) error {
+	logger, flusher, err := logger.NewLogger(c)
+	if err != nil {
+		return err
+	}
+	defer flusher()
+	log := logger.Sugar()
+	storage, err := NewStorageFromContext(c)
+	if err != nil {
+		log.Errorw("error when open storage", "backend", c.String(dbBackendFlag), "err", err)
+		return err
+	}
+	defer storage.Close()
+	// the shard replicas keep their dex rates under the rate worker name too
+	deleted, err := storage.DB.DeleteWorkerStates(c.Context, workers.RateWorkerStateName)
+	if err != nil {
+		log.Errorw("error when delete worker state", "err", err)
+		return err
+	}
+	log.Infow("reset worker state", "name", workers.RateWorkerStateName, "states", deleted)
+	return nil
+}
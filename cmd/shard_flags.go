@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	shardingFlag               = "sharding"
+	replicaIDFlag              = "replica-id"
+	shardHeartbeatIntervalFlag = "shard-heartbeat-interval"
+	shardMemberTTLFlag         = "shard-member-ttl"
+	shardMergeIntervalFlag     = "shard-merge-interval"
+)
+
+// NewShardFlags creates new cli flags for splitting the tokens between replicas.
+func NewShardFlags() []cli.Flag {
+	hostname, _ := os.Hostname()
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    shardingFlag,
+			Usage:   "split the tokens between replicas, the leader discovers the tokens and merges the shard snapshots, needs leader election",
+			EnvVars: []string{"SHARDING"},
+		},
+		&cli.StringFlag{
+			Name:    replicaIDFlag,
+			Usage:   "unique replica id in the shard ring",
+			Value:   hostname,
+			EnvVars: []string{"REPLICA_ID"},
+		},
+		&cli.DurationFlag{
+			Name:    shardHeartbeatIntervalFlag,
+			Usage:   "how often a replica refreshes its shard membership",
+			Value:   5 * time.Second,
+			EnvVars: []string{"SHARD_HEARTBEAT_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:    shardMemberTTLFlag,
+			Usage:   "a replica without heartbeat for this long is dropped from the ring",
+			Value:   15 * time.Second,
+			EnvVars: []string{"SHARD_MEMBER_TTL"},
+		},
+		&cli.DurationFlag{
+			Name:    shardMergeIntervalFlag,
+			Usage:   "how often the leader merges the shard snapshots",
+			Value:   5 * time.Second,
+			EnvVars: []string{"SHARD_MERGE_INTERVAL"},
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	dbBackendFlag  = "db-backend"
+	sqlitePathFlag = "sqlite-path"
+)
+
+const (
+	dbBackendPostgres = "postgres"
+	dbBackendSQLite   = "sqlite"
+	dbBackendMemory   = "memory"
+)
+
+// NewStorageFlags creates new cli flags to choose the storage backend.
+func NewStorageFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    dbBackendFlag,
+			Usage:   "storage backend: postgres, sqlite or memory, the audit log, discoveries, pools, risk and reorg tracking need postgres",
+			Value:   dbBackendPostgres,
+			EnvVars: []string{"DB_BACKEND"},
+		},
+		&cli.StringFlag{
+			Name:    sqlitePathFlag,
+			Usage:   "sqlite database file of the sqlite backend",
+			Value:   "base-token-rate.db",
+			EnvVars: []string{"SQLITE_PATH"},
+		},
+	}
+}
+
+// Storage is the DB of the chosen backend, Postgres and SQL are only set for the postgres backend.
+type Storage struct {
+	DB       db.DB
+	Postgres *db.Postgres
+	SQL      *sqlx.DB
+	close    func() error
+}
+
+func (s *Storage) Close() error {
+	if s.close == nil {
+		return nil
+	}
+	return s.close()
+}
+
+// NewStorageFromContext opens the storage backend from cli flags configuration.
+func NewStorageFromContext(c *cli.Context) (*Storage, error) {
+	switch backend := c.String(dbBackendFlag); backend {
+	case dbBackendPostgres:
+		database, err := NewDBFromContext(c)
+		if err != nil {
+			return nil, err
+		}
+		pg := db.NewPostgres(database)
+		return &Storage{DB: pg, Postgres: pg, SQL: database, close: database.Close}, nil
+	case dbBackendSQLite:
+		sqlite, err := db.NewSQLite(c.String(sqlitePathFlag))
+		if err != nil {
+			return nil, err
+		}
+		return &Storage{DB: sqlite, close: sqlite.Close}, nil
+	case dbBackendMemory:
+		return &Storage{DB: db.NewMemory()}, nil
+	default:
+		return nil, fmt.Errorf("unknown %s %q", dbBackendFlag, backend)
+	}
+}
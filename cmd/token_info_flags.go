@@ -10,6 +10,10 @@ const (
 	tokenInfoWorkerDurationFlag = "token-info-worker-duration"
 	cmcKeyFlag                  = "cmc-key"
 	cmcUrlFlag                  = "cmc-url"
+	cmcCreditBudgetFlag         = "cmc-credit-budget"
+	coingeckoUrlFlag            = "coingecko-url"
+	coingeckoKeyFlag            = "coingecko-key"
+	identityRefreshFlag         = "identity-refresh-interval"
 )
 
 var tokenInfoFlags = []cli.Flag{
@@ -29,6 +33,29 @@ var tokenInfoFlags = []cli.Flag{
 		Value:   time.Hour * 12,
 		EnvVars: []string{"TOKEN_INFO_WORKER_DURATION"},
 	},
+	&cli.IntFlag{
+		Name:    cmcCreditBudgetFlag,
+		Usage:   "max coinmarketcap credits a token info run uses, the next run resumes where it stopped, 0 is unlimited",
+		Value:   0,
+		EnvVars: []string{"CMC_CREDIT_BUDGET"},
+	},
+	&cli.StringFlag{
+		Name:    coingeckoUrlFlag,
+		Usage:   "coingecko url, the coingecko ids are skipped if empty",
+		Value:   "https://api.coingecko.com/api/v3",
+		EnvVars: []string{"COINGECKO_URL"},
+	},
+	&cli.StringFlag{
+		Name:    coingeckoKeyFlag,
+		Usage:   "coingecko demo api key",
+		EnvVars: []string{"COINGECKO_KEY"},
+	},
+	&cli.DurationFlag{
+		Name:    identityRefreshFlag,
+		Usage:   "how often the token identity index is reloaded",
+		Value:   10 * time.Minute,
+		EnvVars: []string{"IDENTITY_REFRESH_INTERVAL"},
+	},
 }
 
 func NewTokenInfoFlags() (flags []cli.Flag) {
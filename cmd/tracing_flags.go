@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/kv-base-hack/base-token-rate/lib/tracing"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	tracingExporterFlag = "tracing-exporter"
+	otlpEndpointFlag    = "otlp-endpoint"
+)
+
+// NewTracingFlags creates new cli flags for opentelemetry tracing.
+func NewTracingFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    tracingExporterFlag,
+			Usage:   "span exporter: none, stdout or otlp",
+			Value:   tracing.ExporterNone,
+			EnvVars: []string{"TRACING_EXPORTER"},
+		},
+		&cli.StringFlag{
+			Name:    otlpEndpointFlag,
+			Usage:   "otlp http endpoint host:port, defaults to the OTEL_EXPORTER_OTLP_ENDPOINT env",
+			EnvVars: []string{"OTLP_ENDPOINT"},
+		},
+	}
+}
@@ -9,11 +9,11 @@ import (
 	"strings"
 )
 
-const _SourcePriceName = "cexdex"
+const _SourcePriceName = "cexdexpool"
 
-var _SourcePriceIndex = [...]uint8{0, 3, 6}
+var _SourcePriceIndex = [...]uint8{0, 3, 6, 10}
 
-const _SourcePriceLowerName = "cexdex"
+const _SourcePriceLowerName = "cexdexpool"
 
 func (i SourcePrice) String() string {
 	i -= 1
@@ -29,20 +29,24 @@ func _SourcePriceNoOp() {
 	var x [1]struct{}
 	_ = x[SourcePriceCex-(1)]
 	_ = x[SourcePriceDex-(2)]
+	_ = x[SourcePricePool-(3)]
 }
 
-var _SourcePriceValues = []SourcePrice{SourcePriceCex, SourcePriceDex}
+var _SourcePriceValues = []SourcePrice{SourcePriceCex, SourcePriceDex, SourcePricePool}
 
 var _SourcePriceNameToValueMap = map[string]SourcePrice{
-	_SourcePriceName[0:3]:      SourcePriceCex,
-	_SourcePriceLowerName[0:3]: SourcePriceCex,
-	_SourcePriceName[3:6]:      SourcePriceDex,
-	_SourcePriceLowerName[3:6]: SourcePriceDex,
+	_SourcePriceName[0:3]:       SourcePriceCex,
+	_SourcePriceLowerName[0:3]:  SourcePriceCex,
+	_SourcePriceName[3:6]:       SourcePriceDex,
+	_SourcePriceLowerName[3:6]:  SourcePriceDex,
+	_SourcePriceName[6:10]:      SourcePricePool,
+	_SourcePriceLowerName[6:10]: SourcePricePool,
 }
 
 var _SourcePriceNames = []string{
 	_SourcePriceName[0:3],
 	_SourcePriceName[3:6],
+	_SourcePriceName[6:10],
 }
 
 // SourcePriceString retrieves an enum value from the enum constants string name.
@@ -1,6 +1,12 @@
 package common
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // enumer -type=Chain -linecomment -json=true -text=true -sql=true
 type Chain uint64
@@ -13,10 +19,52 @@ const (
 type SourcePrice uint64
 
 const (
-	SourcePriceCex SourcePrice = iota + 1 // cex
-	SourcePriceDex                        // dex
+	SourcePriceCex  SourcePrice = iota + 1 // cex
+	SourcePriceDex                         // dex
+	SourcePricePool                        // pool
+)
+
+// enumer -type=PoolKind -linecomment -json=true -text=true -sql=true
+type PoolKind uint64
+
+const (
+	PoolKindV2 PoolKind = iota + 1 // v2
+	PoolKindV3                     // v3
+)
+
+// enumer -type=RefreshTier -linecomment -json=true -text=true -sql=true
+type RefreshTier uint64
+
+const (
+	RefreshTierHot     RefreshTier = iota + 1 // hot
+	RefreshTierWarm                           // warm
+	RefreshTierCold                           // cold
+	RefreshTierDormant                        // dormant
+)
+
+// enumer -type=DepegSeverity -linecomment -json=true -text=true -sql=true
+type DepegSeverity uint64
+
+const (
+	DepegSeverityNone     DepegSeverity = iota + 1 // none
+	DepegSeverityMinor                             // minor
+	DepegSeverityMajor                             // major
+	DepegSeverityCritical                          // critical
+)
+
+// enumer -type=RiskLabel -linecomment -json=true -text=true -sql=true
+type RiskLabel uint64
+
+const (
+	RiskLabelLow      RiskLabel = iota + 1 // low
+	RiskLabelMedium                        // medium
+	RiskLabelHigh                          // high
+	RiskLabelHoneypot                      // honeypot
 )
 
+// TokenFlagQuoteDepeg marks a dex price quoted through a stablecoin which is off its peg
+const TokenFlagQuoteDepeg = "quote_depeg"
+
 type Token struct {
 	UsdPrice    float64     `json:"usdPrice"`
 	Address     string      `json:"tokenAddress"`
@@ -26,6 +74,37 @@ type Token struct {
 	ImageUrl    string      `json:"imageUrl"`
 	DexID       string      `json:"dexId"`
 	Url         string      `json:"url"`
+	// UpdatedAt is when the price was taken in unix seconds, a price restored from the worker
+	// state keeps the time it was taken until it's refreshed
+	UpdatedAt int64 `json:"updatedAt,omitempty"`
+
+	QuoteTokenAddress string   `json:"quoteTokenAddress,omitempty"`
+	Flags             []string `json:"flags,omitempty"`
+
+	// supply from the transfer logs, market cap and fdv are priced with UsdPrice
+	CirculatingSupply float64 `json:"circulatingSupply,omitempty"`
+	TotalSupply       float64 `json:"totalSupply,omitempty"`
+	MarketCap         float64 `json:"marketCap,omitempty"`
+
+	// risk of dex tokens from their trades and transfers
+	RiskLabel   RiskLabel `json:"riskLabel,omitempty"`
+	RiskReasons []string  `json:"riskReasons,omitempty"`
+
+	// from the metadata registry
+	Name     string `json:"name,omitempty"`
+	Decimals *int   `json:"decimals,omitempty"`
+
+	// cross references of the chain and address, the symbol isn't unique
+	CmcID       int    `json:"cmcId,omitempty"`
+	CoingeckoID string `json:"coingeckoId,omitempty"`
+
+	// provenance of dex prices, the pool the price was taken from
+	PairAddress      string  `json:"pairAddress,omitempty"`
+	QuoteTokenSymbol string  `json:"quoteTokenSymbol,omitempty"`
+	LiquidityUsd     float64 `json:"liquidityUsd,omitempty"`
+	VolumeH24        float64 `json:"volumeH24,omitempty"`
+	TxnsH24          *Txns   `json:"txnsH24,omitempty"`
+	Fdv              float64 `json:"fdv,omitempty"`
 
 	PriceChangeM5  float64 `json:"priceChangeM5"`
 	PriceChangeH1  float64 `json:"priceChangeH1"`
@@ -33,20 +112,208 @@ type Token struct {
 	PriceChangeH24 float64 `json:"priceChangeH24"`
 }
 
+// TokenMetadata is the on chain metadata of a token contract, the address is lowercase.
+type TokenMetadata struct {
+	ChainID        string   `json:"chainId" db:"chain_id"`
+	Address        string   `json:"address" db:"address"`
+	Name           string   `json:"name" db:"name"`
+	Symbol         string   `json:"symbol" db:"symbol"`
+	Decimals       *int     `json:"decimals,omitempty" db:"decimals"`
+	TotalSupply    *float64 `json:"totalSupply,omitempty" db:"total_supply"`
+	FirstSeenBlock *int64   `json:"firstSeenBlock,omitempty" db:"first_seen_block"`
+	// FirstMinter is the receiver of the first mint in the transfer logs, not the contract creator
+	FirstMinter string `json:"firstMinter" db:"first_minter"`
+}
+
+// TokenFirstSeen is the first transfer of a token in the logs with the receiver of its first mint.
+type TokenFirstSeen struct {
+	TokenAddress string `db:"token_address"`
+	Block        int64  `db:"first_seen_block"`
+	FirstMinter  string `db:"first_minter"`
+}
+
+// TradeLog is a swap of the trade logs written by the indexer.
+type TradeLog struct {
+	BlockNumber     int64   `db:"block_number"`
+	BlockHash       string  `db:"block_hash"`
+	PoolAddress     string  `db:"pool_address"`
+	TokenInAddress  string  `db:"token_in_address"`
+	TokenOutAddress string  `db:"token_out_address"`
+	AmountIn        float64 `db:"amount_in"`
+	AmountOut       float64 `db:"amount_out"`
+}
+
+// TransferLog is a token transfer of the transfer logs written by the indexer.
+type TransferLog struct {
+	BlockNumber  int64   `db:"block_number"`
+	BlockHash    string  `db:"block_hash"`
+	TokenAddress string  `db:"token_address"`
+	FromAddress  string  `db:"from_address"`
+	ToAddress    string  `db:"to_address"`
+	Amount       float64 `db:"amount"`
+}
+
+// SeenToken is a token of the trade or transfer logs with the range of blocks it was seen in, the address is lowercase.
+type SeenToken struct {
+	ChainID        string `db:"chain_id"`
+	Address        string `db:"address"`
+	FirstSeenBlock int64  `db:"first_seen_block"`
+	LastSeenBlock  int64  `db:"last_seen_block"`
+}
+
+// SeenTokenCursor is the position after the last seen token of a page, the zero value starts from the first page.
+type SeenTokenCursor struct {
+	LastSeenBlock int64
+	Address       string
+}
+
+// TokenDiscovery is a token the rate worker saw for the first time, the address is lowercase.
+type TokenDiscovery struct {
+	ChainID         string    `json:"chainId" db:"chain_id"`
+	Address         string    `json:"address" db:"address"`
+	FirstSeenBlock  int64     `json:"firstSeenBlock" db:"first_seen_block"`
+	FirstSeenAt     time.Time `json:"firstSeenAt" db:"first_seen_at"`
+	FirstTradeBlock *int64    `json:"firstTradeBlock,omitempty" db:"first_trade_block"`
+	// FirstMinter is the receiver of the first mint, the deployer isn't resolved
+	FirstMinter string `json:"firstMinter,omitempty" db:"first_minter"`
+	// set once the token got its first dex price
+	InitialPriceUsd     *float64   `json:"initialPriceUsd,omitempty" db:"initial_price_usd"`
+	InitialLiquidityUsd *float64   `json:"initialLiquidityUsd,omitempty" db:"initial_liquidity_usd"`
+	PricedAt            *time.Time `json:"pricedAt,omitempty" db:"priced_at"`
+}
+
+// TokenDiscoveryCursor is the position after the last discovery of a page, the zero value starts from the newest.
+type TokenDiscoveryCursor struct {
+	FirstSeenAt time.Time
+	Address     string
+}
+
+const (
+	DiscoveryEventDiscovered = "discovered"
+	DiscoveryEventPriced     = "priced"
+)
+
+type DiscoveryEvent struct {
+	Event     string         `json:"event"`
+	Discovery TokenDiscovery `json:"discovery"`
+}
+
+// TokenRisk is the risk of a token and the signals it was computed from.
+type TokenRisk struct {
+	Label      RiskLabel `json:"label"`
+	Reasons    []string  `json:"reasons"`
+	Buys       int64     `json:"buys"`
+	Sells      int64     `json:"sells"`
+	Holders    int64     `json:"holders"`
+	Top10Share float64   `json:"top10Share"`
+}
+
+type TradeSides struct {
+	TokenAddress string `db:"token_address"`
+	Buys         int64  `db:"buys"`
+	Sells        int64  `db:"sells"`
+}
+
+type HolderConcentration struct {
+	TokenAddress string  `db:"token_address"`
+	Holders      int64   `db:"holders"`
+	Top10Share   float64 `db:"top10_share"`
+}
+
+type TransferSenders struct {
+	TokenAddress string `db:"token_address"`
+	Transfers    int64  `db:"transfers"`
+	Senders      int64  `db:"senders"`
+}
+
+// LiquidityPool is a registered pool, the addresses are lowercase.
+type LiquidityPool struct {
+	ChainID        string   `json:"chainId" db:"chain_id"`
+	Address        string   `json:"address" db:"address"`
+	Kind           PoolKind `json:"kind" db:"kind"`
+	Factory        string   `json:"factory" db:"factory"`
+	Token0         string   `json:"token0" db:"token0"`
+	Token1         string   `json:"token1" db:"token1"`
+	Fee            int64    `json:"fee" db:"fee"`
+	FirstSeenBlock int64    `json:"firstSeenBlock" db:"first_seen_block"`
+}
+
+// PoolReserve is a pool state observation, reserves are in token units and Price0 is the price of token0 in token1.
+type PoolReserve struct {
+	PoolAddress  string    `json:"poolAddress" db:"pool_address"`
+	BlockNumber  int64     `json:"blockNumber" db:"block_number"`
+	Reserve0     float64   `json:"reserve0" db:"reserve0"`
+	Reserve1     float64   `json:"reserve1" db:"reserve1"`
+	SqrtPriceX96 string    `json:"sqrtPriceX96,omitempty" db:"sqrt_price_x96"`
+	Price0       float64   `json:"price0" db:"price0"`
+	ObservedAt   time.Time `json:"observedAt" db:"observed_at"`
+}
+
+// Anchor is a token with a known usd price other tokens are priced against in pools.
+type Anchor struct {
+	Symbol  string
+	Address string
+	// CexSymbol is the binance pair against USDT giving the price, a stable anchor without it is worth
+	// its depeg monitor price, $1 until it is known
+	CexSymbol string
+	Stable    bool
+}
+
+var BaseAnchors = []Anchor{
+	{Symbol: "WETH", Address: "0x4200000000000000000000000000000000000006", CexSymbol: "ETHUSDT"},
+	{Symbol: "USDC", Address: "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", CexSymbol: "USDCUSDT", Stable: true},
+	{Symbol: "USDbC", Address: "0xd9aaec86b65d86f6a7b5b1b0c42ffa531710b6ca", Stable: true},
+	{Symbol: "DAI", Address: "0x50c5725949a6f0c72e6c4a641f24049a917db0cb", CexSymbol: "DAIUSDT", Stable: true},
+}
+
+// BaseFactories are the factories of the uniswap v2 and v3 pools on base.
+var BaseFactories = []string{
+	"0x8909dc15e40173ff4699343b6eb8132c65e18ec6",
+	"0x33128a8fc17869897dce68ed026d694621f6fdfd",
+}
+
+// Trade is a swap of the trade logs, the addresses are lowercase and the amounts in token units.
+type Trade struct {
+	BlockNumber int64   `db:"block_number"`
+	TokenIn     string  `db:"token_in_address"`
+	TokenOut    string  `db:"token_out_address"`
+	AmountIn    float64 `db:"amount_in"`
+	AmountOut   float64 `db:"amount_out"`
+}
+
+// Candle is the usd price of a token over IntervalBlocks blocks from StartBlock.
+type Candle struct {
+	ChainID        string  `json:"chainId" db:"chain_id"`
+	TokenAddress   string  `json:"tokenAddress" db:"token_address"`
+	IntervalBlocks int64   `json:"intervalBlocks" db:"interval_blocks"`
+	StartBlock     int64   `json:"startBlock" db:"start_block"`
+	Open           float64 `json:"open" db:"open"`
+	High           float64 `json:"high" db:"high"`
+	Low            float64 `json:"low" db:"low"`
+	Close          float64 `json:"close" db:"close"`
+	VolumeUsd      float64 `json:"volumeUsd" db:"volume_usd"`
+	Trades         int64   `json:"trades" db:"trades"`
+}
+
+type TokenSupply struct {
+	TotalSupply       float64 `json:"totalSupply"`
+	CirculatingSupply float64 `json:"circulatingSupply"`
+}
+
 type TokenInfo struct {
-	ID                int       `json:"id"`
-	Name              string    `json:"name"`
-	Symbol            string    `json:"symbol"`
-	Slug              string    `json:"slug"`
-	CmcRank           int       `json:"cmc_rank,omitempty"`
-	NumMarketPairs    int       `json:"num_market_pairs"`
-	CirculatingSupply float64   `json:"circulating_supply"`
-	TotalSupply       float64   `json:"total_supply"`
-	MaxSupply         float64   `json:"max_supply"`
-	LastUpdated       time.Time `json:"last_updated"`
-	DateAdded         time.Time `json:"date_added"`
-	Tags              []string  `json:"tags"`
-	Platform          any       `json:"platform"`
+	ID                int                    `json:"id"`
+	Name              string                 `json:"name"`
+	Symbol            string                 `json:"symbol"`
+	Slug              string                 `json:"slug"`
+	CmcRank           int                    `json:"cmc_rank,omitempty"`
+	NumMarketPairs    int                    `json:"num_market_pairs"`
+	CirculatingSupply float64                `json:"circulating_supply"`
+	TotalSupply       float64                `json:"total_supply"`
+	MaxSupply         float64                `json:"max_supply"`
+	LastUpdated       time.Time              `json:"last_updated"`
+	DateAdded         time.Time              `json:"date_added"`
+	Tags              []string               `json:"tags"`
+	Platform          *CoinMarketCapPlatform `json:"platform"`
 	Quote             struct {
 		Usd struct {
 			Price            float64   `json:"price"`
@@ -79,6 +346,40 @@ type CoinMarketCapPlatform struct {
 	TokenAddress string `json:"token_address"`
 }
 
+type CoinMarketCapUrls struct {
+	Website      []string `json:"website"`
+	TechnicalDoc []string `json:"technical_doc"`
+	Twitter      []string `json:"twitter"`
+	Reddit       []string `json:"reddit"`
+	MessageBoard []string `json:"message_board"`
+	Chat         []string `json:"chat"`
+	Explorer     []string `json:"explorer"`
+	SourceCode   []string `json:"source_code"`
+}
+
+type CoinMarketCapContractAddress struct {
+	ContractAddress string `json:"contract_address"`
+	Platform        struct {
+		Name string `json:"name"`
+	} `json:"platform"`
+}
+
+type CoinMarketCapMetadata struct {
+	ID              int                            `json:"id"`
+	Name            string                         `json:"name"`
+	Symbol          string                         `json:"symbol"`
+	Slug            string                         `json:"slug"`
+	Logo            string                         `json:"logo"`
+	Urls            CoinMarketCapUrls              `json:"urls"`
+	Platform        *CoinMarketCapPlatform         `json:"platform"`
+	ContractAddress []CoinMarketCapContractAddress `json:"contract_address"`
+}
+
+type CoinMarketCapMetadataInfo struct {
+	Data   map[string]CoinMarketCapMetadata `json:"data"`
+	Status CoinMarketCapStatus              `json:"status"`
+}
+
 type CoinMarketCapStatus struct {
 	Timestamp    time.Time `json:"timestamp"`
 	ErrorCode    int       `json:"error_code"`
@@ -96,10 +397,11 @@ type Pair struct {
 	BaseToken  PairToken `json:"baseToken"`
 	QuoteToken PairToken `json:"quoteToken"`
 
-	ChainID string `json:"chainId"`
-	DexID   string `json:"dexId"`
-	Url     string `json:"url"`
-	Volume  struct {
+	ChainID     string `json:"chainId"`
+	DexID       string `json:"dexId"`
+	Url         string `json:"url"`
+	PairAddress string `json:"pairAddress"`
+	Volume      struct {
 		M5  float64 `json:"m5"`
 		H1  float64 `json:"h1"`
 		H6  float64 `json:"h6"`
@@ -112,22 +414,10 @@ type Pair struct {
 		H24 float64 `json:"h24"`
 	} `json:"priceChange"`
 	Txns struct {
-		M5 struct {
-			Buys  int64 `json:"buys"`
-			Sells int64 `json:"sell"`
-		} `json:"m5"`
-		H1 struct {
-			Buys  int64 `json:"buys"`
-			Sells int64 `json:"sell"`
-		} `json:"h1"`
-		H6 struct {
-			Buys  int64 `json:"buys"`
-			Sells int64 `json:"sell"`
-		} `json:"h6"`
-		H24 struct {
-			Buys  int64 `json:"buys"`
-			Sells int64 `json:"sell"`
-		} `json:"h24"`
+		M5  Txns `json:"m5"`
+		H1  Txns `json:"h1"`
+		H6  Txns `json:"h6"`
+		H24 Txns `json:"h24"`
 	} `json:"txns"`
 	Liquidity struct {
 		Usd float64 `json:"usd"`
@@ -138,6 +428,31 @@ type Pair struct {
 	Fdv float64 `json:"fdv"`
 }
 
+type Txns struct {
+	Buys  int64 `json:"buys"`
+	Sells int64 `json:"sells"`
+}
+
+// Pool is a dex pair a token trades in.
+type Pool struct {
+	PairAddress       string  `json:"pairAddress"`
+	ChainID           string  `json:"chainId"`
+	DexID             string  `json:"dexId"`
+	Url               string  `json:"url"`
+	QuoteTokenAddress string  `json:"quoteTokenAddress"`
+	QuoteTokenSymbol  string  `json:"quoteTokenSymbol"`
+	PriceUsd          float64 `json:"priceUsd"`
+	LiquidityUsd      float64 `json:"liquidityUsd"`
+	VolumeH24         float64 `json:"volumeH24"`
+	TxnsH24           Txns    `json:"txnsH24"`
+}
+
+type RedisTokenPools struct {
+	UpdatedTime  int64  `json:"updated_time"`
+	TokenAddress string `json:"token_address"`
+	Pools        []Pool `json:"pools"`
+}
+
 type PairToken struct {
 	Address string `json:"address"`
 	Name    string `json:"name"`
@@ -147,9 +462,16 @@ type PairToken struct {
 type RedisTokens struct {
 	UpdatedTime int64            `json:"updated_time"`
 	Tokens      []RedisTokenInfo `json:"tokens"`
+	// Complete is false when the listing was cut short, NextStart is where the next run resumes
+	Complete  bool  `json:"complete"`
+	NextStart int64 `json:"next_start,omitempty"`
 }
 
 type RedisTokenInfo struct {
+	ID                    int      `json:"id"`
+	Slug                  string   `json:"slug"`
+	Platform              string   `json:"platform,omitempty"`
+	TokenAddress          string   `json:"token_address,omitempty"`
 	Name                  string   `json:"name"`
 	Symbol                string   `json:"symbol"`
 	CirculatingSupply     float64  `json:"circulating_supply"`
@@ -164,3 +486,148 @@ type RedisTokenInfo struct {
 	PercentChange24H      float64  `json:"percent_change_24h"`
 	PercentChange7D       float64  `json:"percent_change_7d"`
 }
+
+// RedisTokenMetadata is the coinmarketcap info of a token on a chain, with its logo and urls.
+type RedisTokenMetadata struct {
+	RedisTokenInfo
+	Logo        string            `json:"logo,omitempty"`
+	Urls        CoinMarketCapUrls `json:"urls"`
+	CoingeckoID string            `json:"coingecko_id,omitempty"`
+}
+
+type CoinGeckoCoin struct {
+	ID        string            `json:"id"`
+	Symbol    string            `json:"symbol"`
+	Name      string            `json:"name"`
+	Platforms map[string]string `json:"platforms"`
+}
+
+// TokenIdentity is the canonical identity of a token, the chain and address, with its cross references.
+type TokenIdentity struct {
+	ChainID     string `json:"chainId"`
+	Address     string `json:"tokenAddress"`
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name,omitempty"`
+	CmcID       int    `json:"cmcId,omitempty"`
+	CoingeckoID string `json:"coingeckoId,omitempty"`
+}
+
+func (i TokenIdentity) ID() string {
+	return i.ChainID + ":" + strings.ToLower(i.Address)
+}
+
+const (
+	// TokenFlagCmcListed is set when coinmarketcap lists the token at this address
+	TokenFlagCmcListed = "cmc_listed"
+	// TokenFlagCoingeckoListed is set when coingecko lists the token at this address
+	TokenFlagCoingeckoListed = "coingecko_listed"
+	// TokenFlagCexListed is set when the price comes from a centralized exchange
+	TokenFlagCexListed = "cex_listed"
+	// TokenFlagSymbolCollision is set on an unlisted token sharing its symbol with a listed one
+	TokenFlagSymbolCollision = "symbol_collision"
+)
+
+// TokenCandidate is a token matching a symbol search, the best match has the lowest rank.
+type TokenCandidate struct {
+	TokenIdentity
+	Rank         int      `json:"rank"`
+	UsdPrice     float64  `json:"usdPrice"`
+	SourcePrice  string   `json:"sourcePrice,omitempty"`
+	LiquidityUsd float64  `json:"liquidityUsd,omitempty"`
+	Flags        []string `json:"flags"`
+}
+
+// RedisTokenIndex is the coinmarketcap info of the tokens of a chain by lowercase contract address.
+type RedisTokenIndex struct {
+	UpdatedTime int64                         `json:"updated_time"`
+	Chain       string                        `json:"chain"`
+	Tokens      map[string]RedisTokenMetadata `json:"tokens"`
+}
+
+// RedisCoingeckoIndex is the coingecko coins of a chain by lowercase contract address.
+type RedisCoingeckoIndex struct {
+	UpdatedTime int64                    `json:"updated_time"`
+	Chain       string                   `json:"chain"`
+	Tokens      map[string]CoinGeckoCoin `json:"tokens"`
+}
+
+type Stablecoin struct {
+	Symbol  string
+	Address string
+	ChainID string
+	// CexSymbol is the binance pair against USDT, empty if not listed
+	CexSymbol string
+}
+
+var BaseStablecoins = []Stablecoin{
+	{Symbol: "USDC", Address: "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", ChainID: "base", CexSymbol: "USDCUSDT"},
+	{Symbol: "USDbC", Address: "0xd9aaec86b65d86f6a7b5b1b0c42ffa531710b6ca", ChainID: "base"},
+	{Symbol: "DAI", Address: "0x50c5725949a6f0c72e6c4a641f24049a917db0cb", ChainID: "base", CexSymbol: "DAIUSDT"},
+	{Symbol: "USDT", Address: "0xfde4c96c8593536e31f229ea8f37b2ada2699bb2", ChainID: "base"},
+}
+
+type StablecoinStatus struct {
+	Symbol   string  `json:"symbol"`
+	Address  string  `json:"address"`
+	ChainID  string  `json:"chainId"`
+	CexPrice float64 `json:"cexPrice,omitempty"`
+	DexPrice float64 `json:"dexPrice,omitempty"`
+	// Deviation is the largest distance from $1 among the sources, in percent
+	Deviation float64       `json:"deviation"`
+	Severity  DepegSeverity `json:"severity"`
+}
+
+type RedisStablecoinStatus struct {
+	UpdatedTime int64              `json:"updated_time"`
+	Stablecoins []StablecoinStatus `json:"stablecoins"`
+}
+
+// RejectedQuote is a dex pair which wasn't used for the published price and why.
+type RejectedQuote struct {
+	DexID        string  `json:"dexId"`
+	Url          string  `json:"url"`
+	PriceUsd     float64 `json:"priceUsd"`
+	LiquidityUsd float64 `json:"liquidityUsd"`
+	VolumeH24    float64 `json:"volumeH24"`
+	Reason       string  `json:"reason"`
+}
+
+type RejectedQuotes []RejectedQuote
+
+func (q RejectedQuotes) Value() (driver.Value, error) {
+	if q == nil {
+		q = RejectedQuotes{}
+	}
+	data, err := json.Marshal(q)
+	return string(data), err
+}
+
+func (q *RejectedQuotes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*q = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, q)
+	case string:
+		return json.Unmarshal([]byte(v), q)
+	default:
+		return fmt.Errorf("invalid value of RejectedQuotes: %[1]T(%[1]v)", value)
+	}
+}
+
+// PriceAudit is a published price with the pair it came from.
+type PriceAudit struct {
+	TokenAddress string         `json:"tokenAddress" db:"token_address"`
+	ChainID      string         `json:"chainId" db:"chain_id"`
+	Symbol       string         `json:"symbol" db:"symbol"`
+	UsdPrice     float64        `json:"usdPrice" db:"usd_price"`
+	SourcePrice  SourcePrice    `json:"sourcePrice" db:"source_price"`
+	DexID        string         `json:"dexId" db:"dex_id"`
+	Url          string         `json:"url" db:"url"`
+	PairAddress  string         `json:"pairAddress" db:"pair_address"`
+	LiquidityUsd float64        `json:"liquidityUsd" db:"liquidity_usd"`
+	VolumeH24    float64        `json:"volumeH24" db:"volume_h24"`
+	Rejected     RejectedQuotes `json:"rejected" db:"rejected"`
+	PublishedAt  time.Time      `json:"publishedAt" db:"published_at"`
+}
@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type RuleType string
+
+const (
+	// RulePercentChange fires when the price moved more than Percent over Window
+	RulePercentChange RuleType = "percent_change"
+	// RuleCrossing fires when the price crosses Level in either direction
+	RuleCrossing RuleType = "crossing"
+	// RuleDepeg fires when a stablecoin is more than Percent away from $1
+	RuleDepeg RuleType = "depeg"
+	// RuleDisappeared fires when a token is missing from the snapshot
+	RuleDisappeared RuleType = "disappeared"
+)
+
+type SinkType string
+
+const (
+	SinkWebhook  SinkType = "webhook"
+	SinkSlack    SinkType = "slack"
+	SinkDiscord  SinkType = "discord"
+	SinkTelegram SinkType = "telegram"
+)
+
+// Duration is a time.Duration written as a string like "5m" in the config file.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration should be a string, got %s", data)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+type Rule struct {
+	Name string   `json:"name"`
+	Type RuleType `json:"type"`
+	// Tokens are the token addresses the rule applies to, all tokens if empty
+	Tokens   []string `json:"tokens"`
+	Percent  float64  `json:"percent"`
+	Window   Duration `json:"window"`
+	Level    float64  `json:"level"`
+	Cooldown Duration `json:"cooldown"`
+	Sinks    []string `json:"sinks"`
+}
+
+type SinkConfig struct {
+	Name string   `json:"name"`
+	Type SinkType `json:"type"`
+	Url  string   `json:"url"`
+	// ChatID is the telegram chat, the bot token is part of Url
+	ChatID string `json:"chatId"`
+}
+
+type Config struct {
+	Rules []Rule       `json:"rules"`
+	Sinks []SinkConfig `json:"sinks"`
+}
+
+// LoadConfig reads and validates the alert rules file.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, err
+	}
+	sinks := map[string]bool{}
+	for _, s := range config.Sinks {
+		switch s.Type {
+		case SinkWebhook, SinkSlack, SinkDiscord, SinkTelegram:
+		default:
+			return Config{}, fmt.Errorf("sink %s has unknown type %s", s.Name, s.Type)
+		}
+		sinks[s.Name] = true
+	}
+	names := map[string]bool{}
+	for i, r := range config.Rules {
+		if names[r.Name] {
+			return Config{}, fmt.Errorf("rule name %s is used twice", r.Name)
+		}
+		names[r.Name] = true
+		switch r.Type {
+		case RulePercentChange, RuleCrossing, RuleDepeg, RuleDisappeared:
+		default:
+			return Config{}, fmt.Errorf("rule %s has unknown type %s", r.Name, r.Type)
+		}
+		if r.Type == RulePercentChange && r.Window <= 0 {
+			return Config{}, fmt.Errorf("rule %s needs a window", r.Name)
+		}
+		// a 0 threshold would fire on every snapshot
+		if (r.Type == RulePercentChange || r.Type == RuleDepeg) && r.Percent <= 0 {
+			return Config{}, fmt.Errorf("rule %s needs a percent above 0", r.Name)
+		}
+		for _, s := range r.Sinks {
+			if !sinks[s] {
+				return Config{}, fmt.Errorf("rule %s uses unknown sink %s", r.Name, s)
+			}
+		}
+		for j, t := range r.Tokens {
+			config.Rules[i].Tokens[j] = strings.ToLower(t)
+		}
+	}
+	return config, nil
+}
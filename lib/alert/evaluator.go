@@ -0,0 +1,215 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+type Alert struct {
+	Rule     string    `json:"rule"`
+	Type     RuleType  `json:"type"`
+	Token    string    `json:"token"`
+	Symbol   string    `json:"symbol"`
+	ChainID  string    `json:"chainId"`
+	Price    float64   `json:"price"`
+	Previous float64   `json:"previous,omitempty"`
+	Change   float64   `json:"change,omitempty"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+}
+
+type sample struct {
+	at    time.Time
+	price float64
+}
+
+// Evaluator checks the rules against each published snapshot. An alert fires when its condition
+// becomes true and again every cooldown while it stays true, it's re-armed once the condition clears
+// and the cooldown passed, so a flapping condition or token fires once per cooldown.
+type Evaluator struct {
+	rules     []Rule
+	cooldowns map[string]time.Duration
+	maxWindow time.Duration
+	history   map[string][]sample
+	last      map[string]common.Token
+	// last time a rule fired for a token, by rule name and token key
+	active map[string]time.Time
+}
+
+func NewEvaluator(rules []Rule) *Evaluator {
+	maxWindow := time.Duration(0)
+	cooldowns := make(map[string]time.Duration, len(rules))
+	for _, r := range rules {
+		cooldowns[r.Name] = time.Duration(r.Cooldown)
+		if time.Duration(r.Window) > maxWindow {
+			maxWindow = time.Duration(r.Window)
+		}
+	}
+	return &Evaluator{
+		rules:     rules,
+		cooldowns: cooldowns,
+		maxWindow: maxWindow,
+		history:   make(map[string][]sample),
+		last:      make(map[string]common.Token),
+		active:    make(map[string]time.Time),
+	}
+}
+
+func tokenKey(t common.Token) string {
+	return t.ChainID + ":" + strings.ToLower(t.Address)
+}
+
+func (r Rule) matches(t common.Token) bool {
+	if len(r.Tokens) == 0 {
+		return true
+	}
+	address := strings.ToLower(t.Address)
+	for _, a := range r.Tokens {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Evaluator) Evaluate(tokens []common.Token, at time.Time) []Alert {
+	current := make(map[string]common.Token, len(tokens))
+	for _, t := range tokens {
+		current[tokenKey(t)] = t
+		e.record(tokenKey(t), t.UsdPrice, at)
+	}
+
+	alerts := []Alert{}
+	for _, r := range e.rules {
+		if r.Type == RuleDisappeared {
+			for key, t := range e.last {
+				if !r.matches(t) {
+					continue
+				}
+				_, exist := current[key]
+				alert := Alert{Previous: t.UsdPrice, Message: fmt.Sprintf("%s (%s) disappeared from the snapshot", t.Symbol, t.Address)}
+				if a, fire := e.check(r, t, key, !exist, alert, at); fire {
+					alerts = append(alerts, a)
+				}
+			}
+			continue
+		}
+		for key, t := range current {
+			if !r.matches(t) {
+				continue
+			}
+			active, alert := e.condition(r, key, t, at)
+			if a, fire := e.check(r, t, key, active, alert, at); fire {
+				alerts = append(alerts, a)
+			}
+		}
+	}
+	e.last = current
+	e.prune(current, at)
+	return alerts
+}
+
+// prune drops the history of the tokens which left the snapshot, and their fired alerts once the
+// cooldown passed, a token dropping out and coming back doesn't fire its disappeared alert again
+// within the cooldown.
+func (e *Evaluator) prune(current map[string]common.Token, at time.Time) {
+	for key := range e.history {
+		if _, exist := current[key]; !exist {
+			delete(e.history, key)
+		}
+	}
+	for activeKey, fired := range e.active {
+		separator := strings.LastIndex(activeKey, "|")
+		if _, exist := current[activeKey[separator+1:]]; exist {
+			continue
+		}
+		if at.Sub(fired) >= e.cooldowns[activeKey[:separator]] {
+			delete(e.active, activeKey)
+		}
+	}
+}
+
+func (e *Evaluator) condition(r Rule, key string, t common.Token, at time.Time) (bool, Alert) {
+	switch r.Type {
+	case RulePercentChange:
+		base, ok := e.baseline(key, at.Add(-time.Duration(r.Window)))
+		if !ok || base == 0 {
+			return false, Alert{}
+		}
+		change := (t.UsdPrice - base) / base * 100
+		return math.Abs(change) >= r.Percent, Alert{
+			Previous: base,
+			Change:   change,
+			Message: fmt.Sprintf("%s (%s) moved %.2f%% in %s: %g -> %g",
+				t.Symbol, t.Address, change, time.Duration(r.Window), base, t.UsdPrice),
+		}
+	case RuleCrossing:
+		prev, exist := e.last[key]
+		if !exist || prev.UsdPrice == 0 {
+			return false, Alert{}
+		}
+		crossed := (prev.UsdPrice < r.Level) != (t.UsdPrice < r.Level)
+		return crossed, Alert{
+			Previous: prev.UsdPrice,
+			Message:  fmt.Sprintf("%s (%s) crossed %g: %g -> %g", t.Symbol, t.Address, r.Level, prev.UsdPrice, t.UsdPrice),
+		}
+	case RuleDepeg:
+		change := (t.UsdPrice - 1) * 100
+		return math.Abs(change) >= r.Percent, Alert{
+			Change:  change,
+			Message: fmt.Sprintf("%s (%s) is off its $1 peg: %g", t.Symbol, t.Address, t.UsdPrice),
+		}
+	}
+	return false, Alert{}
+}
+
+// check applies dedup and cooldown to a rule condition.
+func (e *Evaluator) check(r Rule, t common.Token, key string, active bool, alert Alert, at time.Time) (Alert, bool) {
+	activeKey := r.Name + "|" + key
+	last, fired := e.active[activeKey]
+	cooling := fired && at.Sub(last) < time.Duration(r.Cooldown)
+	if !active {
+		// re-armed once the cooldown passed
+		if fired && !cooling {
+			delete(e.active, activeKey)
+		}
+		return Alert{}, false
+	}
+	if cooling {
+		return Alert{}, false
+	}
+	e.active[activeKey] = at
+
+	alert.Rule = r.Name
+	alert.Type = r.Type
+	alert.Token = t.Address
+	alert.Symbol = t.Symbol
+	alert.ChainID = t.ChainID
+	alert.Price = t.UsdPrice
+	alert.Time = at
+	return alert, true
+}
+
+func (e *Evaluator) record(key string, price float64, at time.Time) {
+	samples := append(e.history[key], sample{at: at, price: price})
+	// keep one sample older than the longest window as its baseline
+	for len(samples) > 1 && !samples[1].at.After(at.Add(-e.maxWindow)) {
+		samples = samples[1:]
+	}
+	e.history[key] = samples
+}
+
+// baseline returns the last price seen at or before since.
+func (e *Evaluator) baseline(key string, since time.Time) (float64, bool) {
+	samples := e.history[key]
+	for i := len(samples) - 1; i >= 0; i-- {
+		if !samples[i].at.After(since) {
+			return samples[i].price, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,108 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+const testToken = "0x000000000000000000000000000000000000000a"
+
+func snapshot(price float64) []common.Token {
+	return []common.Token{{Address: testToken, Symbol: "TKN", ChainID: "base", UsdPrice: price}}
+}
+
+type step struct {
+	after  time.Duration
+	tokens []common.Token
+	fires  bool
+}
+
+func runSteps(t *testing.T, rule Rule, steps []step) {
+	t.Helper()
+	e := NewEvaluator([]Rule{rule})
+	start := time.Unix(1_700_000_000, 0)
+	for i, s := range steps {
+		alerts := e.Evaluate(s.tokens, start.Add(s.after))
+		if fired := len(alerts) > 0; fired != s.fires {
+			t.Fatalf("step %d at %s: fired %v, want %v (%v)", i, s.after, fired, s.fires, alerts)
+		}
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		steps []step
+	}{
+		{
+			name: "crossing fires on the crossing only",
+			rule: Rule{Name: "cross", Type: RuleCrossing, Level: 100},
+			steps: []step{
+				{0, snapshot(90), false},
+				{time.Minute, snapshot(110), true},
+				{2 * time.Minute, snapshot(120), false},
+				{3 * time.Minute, snapshot(95), true},
+			},
+		},
+		{
+			name: "percent change over the window",
+			rule: Rule{Name: "move", Type: RulePercentChange, Percent: 20, Window: Duration(15 * time.Minute), Cooldown: Duration(time.Hour)},
+			steps: []step{
+				{0, snapshot(100), false},
+				{5 * time.Minute, snapshot(110), false},
+				{15 * time.Minute, snapshot(125), true},
+			},
+		},
+		{
+			name: "cooldown while the condition stays true",
+			rule: Rule{Name: "peg", Type: RuleDepeg, Percent: 1, Cooldown: Duration(time.Hour)},
+			steps: []step{
+				{0, snapshot(0.95), true},
+				{10 * time.Minute, snapshot(0.95), false},
+				{59 * time.Minute, snapshot(0.95), false},
+				{time.Hour, snapshot(0.95), true},
+			},
+		},
+		{
+			name: "re-armed once cleared after the cooldown",
+			rule: Rule{Name: "peg", Type: RuleDepeg, Percent: 1, Cooldown: Duration(time.Hour)},
+			steps: []step{
+				{0, snapshot(0.95), true},
+				{10 * time.Minute, snapshot(1), false},
+				// flapping within the cooldown doesn't fire again
+				{20 * time.Minute, snapshot(0.95), false},
+				{70 * time.Minute, snapshot(1), false},
+				{80 * time.Minute, snapshot(0.95), true},
+			},
+		},
+		{
+			name: "disappeared and reappeared within the cooldown",
+			rule: Rule{Name: "gone", Type: RuleDisappeared, Cooldown: Duration(6 * time.Hour)},
+			steps: []step{
+				{0, snapshot(1), false},
+				{time.Minute, nil, true},
+				{2 * time.Minute, nil, false},
+				{3 * time.Minute, snapshot(1), false},
+				{4 * time.Minute, snapshot(1), false},
+				{5 * time.Minute, nil, false},
+				{7 * time.Hour, snapshot(1), false},
+				{7*time.Hour + time.Minute, nil, true},
+			},
+		},
+		{
+			name: "rule of other tokens",
+			rule: Rule{Name: "peg", Type: RuleDepeg, Percent: 1, Tokens: []string{"0x000000000000000000000000000000000000000b"}},
+			steps: []step{
+				{0, snapshot(0.5), false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runSteps(t, tt.rule, tt.steps)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package alert
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"go.uber.org/zap"
+)
+
+const (
+	queueSize   = 1000
+	sendTimeout = 10 * time.Second
+)
+
+// Manager evaluates the rules after each snapshot and delivers alerts in the background,
+// so a slow sink doesn't delay the rate worker.
+type Manager struct {
+	log       *zap.SugaredLogger
+	mu        sync.Mutex
+	evaluator *Evaluator
+	rules     map[string]Rule
+	sinks     map[string]Sink
+	queue     chan Alert
+}
+
+func NewManager(log *zap.SugaredLogger, config Config) *Manager {
+	client := &http.Client{Timeout: sendTimeout}
+	sinks := make(map[string]Sink, len(config.Sinks))
+	for _, s := range config.Sinks {
+		sinks[s.Name] = NewHTTPSink(client, s)
+	}
+	rules := make(map[string]Rule, len(config.Rules))
+	for _, r := range config.Rules {
+		rules[r.Name] = r
+	}
+	return &Manager{
+		log:       log,
+		evaluator: NewEvaluator(config.Rules),
+		rules:     rules,
+		sinks:     sinks,
+		queue:     make(chan Alert, queueSize),
+	}
+}
+
+func (m *Manager) OnSnapshot(_ context.Context, tokens []common.Token, at time.Time) {
+	m.mu.Lock()
+	alerts := m.evaluator.Evaluate(tokens, at)
+	m.mu.Unlock()
+	for _, a := range alerts {
+		select {
+		case m.queue <- a:
+		default:
+			m.log.Warnw("alert queue is full, drop alert", "alert", a)
+		}
+	}
+}
+
+func (m *Manager) Run() {
+	for a := range m.queue {
+		m.log.Infow("alert", "alert", a)
+		for _, name := range m.rules[a.Rule].Sinks {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			if err := m.sinks[name].Send(ctx, a); err != nil {
+				m.log.Errorw("error when send alert", "sink", name, "rule", a.Rule, "err", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type Sink interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// HTTPSink posts alerts as json, the payload is shaped for the sink type.
+type HTTPSink struct {
+	client *http.Client
+	config SinkConfig
+}
+
+func NewHTTPSink(client *http.Client, config SinkConfig) *HTTPSink {
+	return &HTTPSink{
+		client: client,
+		config: config,
+	}
+}
+
+func (s *HTTPSink) payload(alert Alert) any {
+	switch s.config.Type {
+	case SinkSlack:
+		return map[string]string{"text": alert.Message}
+	case SinkDiscord:
+		return map[string]string{"content": alert.Message}
+	case SinkTelegram:
+		return map[string]string{"chat_id": s.config.ChatID, "text": alert.Message}
+	default:
+		return alert
+	}
+}
+
+func (s *HTTPSink) Send(ctx context.Context, alert Alert) error {
+	data, err := json.Marshal(s.payload(alert))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.Url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("sink %s returned status %d", s.config.Name, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errProvider = errors.New("provider error")
+
+func TestBreaker(t *testing.T) {
+	type step struct {
+		wait  time.Duration
+		allow bool
+		err   error
+		state State
+	}
+	const cooldown = 20 * time.Millisecond
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "opens after threshold failures",
+			steps: []step{
+				{0, true, errProvider, StateClosed},
+				{0, true, errProvider, StateClosed},
+				{0, true, errProvider, StateOpen},
+				{0, false, nil, StateOpen},
+			},
+		},
+		{
+			name: "a success resets the failures",
+			steps: []step{
+				{0, true, errProvider, StateClosed},
+				{0, true, errProvider, StateClosed},
+				{0, true, nil, StateClosed},
+				{0, true, errProvider, StateClosed},
+				{0, true, errProvider, StateClosed},
+			},
+		},
+		{
+			name: "half-open trial closes on success",
+			steps: []step{
+				{0, true, errProvider, StateClosed},
+				{0, true, errProvider, StateClosed},
+				{0, true, errProvider, StateOpen},
+				{cooldown, true, nil, StateClosed},
+				{0, true, nil, StateClosed},
+			},
+		},
+		{
+			name: "half-open trial reopens on failure",
+			steps: []step{
+				{0, true, errProvider, StateClosed},
+				{0, true, errProvider, StateClosed},
+				{0, true, errProvider, StateOpen},
+				{cooldown, true, errProvider, StateOpen},
+				{0, false, nil, StateOpen},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("test", 3, cooldown)
+			for i, s := range tt.steps {
+				time.Sleep(s.wait)
+				if allow := b.Allow(); allow != s.allow {
+					t.Fatalf("step %d: Allow = %v, want %v", i, allow, s.allow)
+				}
+				if s.allow {
+					b.Record(s.err)
+				}
+				if state := b.State(); state != s.state {
+					t.Fatalf("step %d: state %s, want %s", i, state, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpenLetsOneTrial(t *testing.T) {
+	b := New("test", 1, 0)
+	b.Record(errProvider)
+	if !b.Allow() {
+		t.Fatal("trial request not allowed after the cooldown")
+	}
+	if state := b.State(); state != StateHalfOpen {
+		t.Fatalf("state %s, want %s", state, StateHalfOpen)
+	}
+	if b.Allow() {
+		t.Fatal("second request allowed while the trial is in flight")
+	}
+}
@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const membersKey = "rate_worker_members"
+
+// Membership keeps this replica registered in redis and tracks the live replicas, a replica
+// which hasn't sent a heartbeat within ttl is dropped from the ring.
+type Membership struct {
+	log      *zap.SugaredLogger
+	client   *redis.Client
+	id       string
+	interval time.Duration
+	ttl      time.Duration
+
+	mu      sync.RWMutex
+	members []string
+	ring    *HashRing
+}
+
+func NewMembership(log *zap.SugaredLogger, client *redis.Client, id string, interval, ttl time.Duration) *Membership {
+	return &Membership{
+		log:      log.With("replica", id),
+		client:   client,
+		id:       id,
+		interval: interval,
+		ttl:      ttl,
+		members:  []string{id},
+		ring:     NewHashRing([]string{id}),
+	}
+}
+
+func (m *Membership) ID() string {
+	return m.id
+}
+
+// Members returns the live replicas as of the last heartbeat.
+func (m *Membership) Members() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.members
+}
+
+// Owns reports whether the token address belongs to this replica's shard.
+func (m *Membership) Owns(address string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ring.Owner(strings.ToLower(address)) == m.id
+}
+
+func (m *Membership) Run() {
+	ticker := time.NewTicker(m.interval)
+	for ; ; <-ticker.C {
+		if err := m.heartbeat(); err != nil {
+			m.log.Errorw("error when send membership heartbeat", "err", err)
+		}
+	}
+}
+
+func (m *Membership) heartbeat() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+	defer cancel()
+	now := time.Now()
+	if err := m.client.ZAdd(ctx, membersKey, redis.Z{Score: float64(now.UnixMilli()), Member: m.id}).Err(); err != nil {
+		return err
+	}
+	expired := strconv.FormatInt(now.Add(-m.ttl).UnixMilli(), 10)
+	if err := m.client.ZRemRangeByScore(ctx, membersKey, "-inf", expired).Err(); err != nil {
+		return err
+	}
+	members, err := m.client.ZRange(ctx, membersKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	sort.Strings(members)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if strings.Join(members, ",") != strings.Join(m.members, ",") {
+		m.log.Infow("shard members changed", "members", members)
+		m.members = members
+		m.ring = NewHashRing(members)
+	}
+	return nil
+}
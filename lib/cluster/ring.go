@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// virtualNodes is the number of points each member has on the ring, more points spread keys more evenly.
+const virtualNodes = 64
+
+// HashRing assigns keys to members with consistent hashing, so a membership change only moves
+// the keys of the joining or leaving member.
+type HashRing struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+func NewHashRing(members []string) *HashRing {
+	ring := &HashRing{
+		owners: make(map[uint32]string, len(members)*virtualNodes),
+	}
+	for _, m := range members {
+		for i := 0; i < virtualNodes; i++ {
+			point := crc32.ChecksumIEEE([]byte(m + "#" + strconv.Itoa(i)))
+			ring.points = append(ring.points, point)
+			ring.owners[point] = m
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool {
+		return ring.points[i] < ring.points[j]
+	})
+	return ring
+}
+
+// Owner returns the member owning the key, or an empty string if the ring has no member.
+func (h *HashRing) Owner(key string) string {
+	if len(h.points) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(h.points), func(i int) bool {
+		return h.points[i] >= hash
+	})
+	if i == len(h.points) {
+		i = 0
+	}
+	return h.owners[h.points[i]]
+}
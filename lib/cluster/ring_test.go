@@ -0,0 +1,63 @@
+package cluster
+
+import (
+	"strconv"
+	"testing"
+)
+
+func owners(ring *HashRing, keys int) []string {
+	result := make([]string, keys)
+	for i := range result {
+		result[i] = ring.Owner("0x" + strconv.Itoa(i))
+	}
+	return result
+}
+
+func TestHashRingEmpty(t *testing.T) {
+	if owner := NewHashRing(nil).Owner("0x1"); owner != "" {
+		t.Fatalf("owner of an empty ring = %q", owner)
+	}
+}
+
+func TestHashRingMembershipChange(t *testing.T) {
+	const keys = 2000
+	tests := []struct {
+		name    string
+		before  []string
+		after   []string
+		changed string
+	}{
+		{"member joins", []string{"a", "b", "c"}, []string{"a", "b", "c", "d"}, "d"},
+		{"member leaves", []string{"a", "b", "c", "d"}, []string{"a", "b", "d"}, "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := owners(NewHashRing(tt.before), keys)
+			after := owners(NewHashRing(tt.after), keys)
+			moved := 0
+			for i := range before {
+				if before[i] == after[i] {
+					continue
+				}
+				moved++
+				// only the keys of the joining or leaving member move
+				if before[i] != tt.changed && after[i] != tt.changed {
+					t.Fatalf("key %d moved from %s to %s", i, before[i], after[i])
+				}
+			}
+			if moved == 0 || moved > keys/2 {
+				t.Fatalf("%d of %d keys moved", moved, keys)
+			}
+		})
+	}
+}
+
+func TestHashRingOrderIndependent(t *testing.T) {
+	a := owners(NewHashRing([]string{"a", "b", "c"}), 500)
+	b := owners(NewHashRing([]string{"c", "a", "b"}), 500)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("key %d owned by %s and %s", i, a[i], b[i])
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package erc20
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
+)
+
+// selectors of the erc20 metadata calls
+const (
+	nameSelector        = "0x06fdde03"
+	symbolSelector      = "0x95d89b41"
+	decimalsSelector    = "0x313ce567"
+	totalSupplySelector = "0x18160ddd"
+)
+
+// MaxDecimals is the largest decimals of an erc20 token, decimals is a uint8.
+const MaxDecimals = 255
+
+type Metadata struct {
+	Name   string
+	Symbol string
+	// Decimals and TotalSupply are nil if the contract didn't answer, Decimals is nil too if the
+	// contract returned more than MaxDecimals
+	Decimals    *int
+	TotalSupply *big.Int
+}
+
+// Client reads erc20 metadata with eth_call from a json rpc node.
+type Client struct {
+	client *http.Client
+	url    string
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    url,
+	}
+}
+
+type rpcRequest struct {
+	JsonRpc string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int    `json:"id"`
+	Result string `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// batchCall calls the selectors of the contract in a batch, the result of a call which reverted
+// or returned nothing is missing.
+func (c *Client) batchCall(ctx context.Context, address string, selectors []string) (map[string][]byte, error) {
+	batch := make([]rpcRequest, 0, len(selectors))
+	for i, s := range selectors {
+		batch = append(batch, rpcRequest{
+			JsonRpc: "2.0",
+			ID:      i,
+			Method:  "eth_call",
+			Params:  []interface{}{map[string]string{"to": address, "data": s}, "latest"},
+		})
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		metrics.ObserveProviderRequest(metrics.ProviderRPC, 0, start)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	metrics.ObserveProviderRequest(metrics.ProviderRPC, resp.StatusCode, start)
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rpc status %d: %s", resp.StatusCode, respBody)
+	}
+	var results []rpcResponse
+	if err := json.Unmarshal(respBody, &results); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(results))
+	for _, r := range results {
+		if r.Error != nil || r.ID < 0 || r.ID >= len(selectors) {
+			continue
+		}
+		data, err := hex.DecodeString(strings.TrimPrefix(r.Result, "0x"))
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		result[selectors[r.ID]] = data
+	}
+	return result, nil
+}
+
+// GetMetadata calls name, symbol, decimals and totalSupply in a batch, a call the contract
+// doesn't implement leaves its field empty.
+func (c *Client) GetMetadata(ctx context.Context, address string) (Metadata, error) {
+	results, err := c.batchCall(ctx, address, []string{nameSelector, symbolSelector, decimalsSelector, totalSupplySelector})
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var metadata Metadata
+	for selector, data := range results {
+		switch selector {
+		case nameSelector:
+			metadata.Name = decodeString(data)
+		case symbolSelector:
+			metadata.Symbol = decodeString(data)
+		case decimalsSelector:
+			// the decimals come from an untrusted contract, they scale amounts by 10^decimals
+			value := new(big.Int).SetBytes(data)
+			if value.IsInt64() && value.Int64() <= MaxDecimals {
+				decimals := int(value.Int64())
+				metadata.Decimals = &decimals
+			}
+		case totalSupplySelector:
+			metadata.TotalSupply = new(big.Int).SetBytes(data)
+		}
+	}
+	return metadata, nil
+}
+
+// decodeString decodes an abi encoded string, or a bytes32 as returned by some old tokens.
+func decodeString(data []byte) string {
+	if len(data) >= 64 {
+		// the offset and length come from an untrusted contract, they are compared to the
+		// remaining data before any arithmetic so they can't overflow
+		offset := new(big.Int).SetBytes(data[:32])
+		if offset.Cmp(big.NewInt(int64(len(data)-32))) <= 0 {
+			bg := int(offset.Int64())
+			length := new(big.Int).SetBytes(data[bg : bg+32])
+			if length.Cmp(big.NewInt(int64(len(data)-bg-32))) <= 0 {
+				return string(data[bg+32 : bg+32+int(length.Int64())])
+			}
+		}
+	}
+	if len(data) == 32 {
+		return string(bytes.TrimRight(data, "\x00"))
+	}
+	return ""
+}
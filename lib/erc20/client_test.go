@@ -0,0 +1,47 @@
+package erc20
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func word(value *big.Int) []byte {
+	return value.FillBytes(make([]byte, 32))
+}
+
+func abiString(offset, length *big.Int, data string) []byte {
+	encoded := append(word(offset), word(length)...)
+	padded := make([]byte, (len(data)+31)/32*32)
+	copy(padded, data)
+	return append(encoded, padded...)
+}
+
+func TestDecodeString(t *testing.T) {
+	maxInt64 := new(big.Int).SetUint64(1<<63 - 1)
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"abi string", abiString(big.NewInt(32), big.NewInt(5), "Token"), "Token"},
+		{"empty string", abiString(big.NewInt(32), big.NewInt(0), ""), ""},
+		{"bytes32", append([]byte("TKN"), bytes.Repeat([]byte{0}, 29)...), "TKN"},
+		{"truncated", abiString(big.NewInt(32), big.NewInt(5), "Token")[:40], ""},
+		{"length past the data", abiString(big.NewInt(32), big.NewInt(33), "Token"), ""},
+		{"offset past the data", abiString(big.NewInt(64), big.NewInt(5), "Token"), ""},
+		{"offset near max int64", abiString(maxInt64, big.NewInt(5), "Token"), ""},
+		{"offset max uint256", abiString(maxUint256, big.NewInt(5), "Token"), ""},
+		{"length near max int64", abiString(big.NewInt(32), new(big.Int).Sub(maxInt64, big.NewInt(31)), "Token"), ""},
+		{"length max uint256", abiString(big.NewInt(32), maxUint256, "Token"), ""},
+		{"no data", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeString(tt.data); got != tt.want {
+				t.Errorf("decodeString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
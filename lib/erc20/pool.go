@@ -0,0 +1,122 @@
+package erc20
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// selectors of the uniswap v2 and v3 style pool calls
+const (
+	token0Selector      = "0x0dfe1681"
+	token1Selector      = "0xd21220a7"
+	factorySelector     = "0xc45a0155"
+	getReservesSelector = "0x0902f1ac"
+	slot0Selector       = "0x3850c7bd"
+	feeSelector         = "0xddca3f43"
+	balanceOfSelector   = "0x70a08231"
+	// factory lookups: getPair(token0, token1) of v2 and getPool(token0, token1, fee) of v3
+	getPairSelector = "0xe6a43905"
+	getPoolSelector = "0x1698ee82"
+)
+
+var ErrNotPool = errors.New("contract is not a v2 or v3 pool")
+
+type PoolState struct {
+	Kind    common.PoolKind
+	Factory string
+	Token0  string
+	Token1  string
+	Fee     int64
+	// raw reserves of a v2 pool
+	Reserve0 *big.Int
+	Reserve1 *big.Int
+	// sqrt price of a v3 pool
+	SqrtPriceX96 *big.Int
+}
+
+func decodeAddress(data []byte) string {
+	if len(data) < 32 {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(data[12:32])
+}
+
+func encodeAddress(address string) string {
+	return strings.Repeat("0", 24) + strings.TrimPrefix(strings.ToLower(address), "0x")
+}
+
+// GetPoolState reads the tokens and the reserves or sqrt price of a pool.
+func (c *Client) GetPoolState(ctx context.Context, address string) (PoolState, error) {
+	results, err := c.batchCall(ctx, address, []string{token0Selector, token1Selector, factorySelector,
+		getReservesSelector, slot0Selector, feeSelector})
+	if err != nil {
+		return PoolState{}, err
+	}
+	state := PoolState{
+		Factory: decodeAddress(results[factorySelector]),
+		Token0:  decodeAddress(results[token0Selector]),
+		Token1:  decodeAddress(results[token1Selector]),
+	}
+	if state.Token0 == "" || state.Token1 == "" {
+		return PoolState{}, ErrNotPool
+	}
+	if reserves := results[getReservesSelector]; len(reserves) >= 64 {
+		state.Kind = common.PoolKindV2
+		state.Reserve0 = new(big.Int).SetBytes(reserves[:32])
+		state.Reserve1 = new(big.Int).SetBytes(reserves[32:64])
+		return state, nil
+	}
+	if slot0 := results[slot0Selector]; len(slot0) >= 32 {
+		state.Kind = common.PoolKindV3
+		state.SqrtPriceX96 = new(big.Int).SetBytes(slot0[:32])
+		if fee := results[feeSelector]; len(fee) >= 32 {
+			state.Fee = new(big.Int).SetBytes(fee[:32]).Int64()
+		}
+		return state, nil
+	}
+	return PoolState{}, ErrNotPool
+}
+
+// GetBalance returns the raw balance of the holder.
+func (c *Client) GetBalance(ctx context.Context, token, holder string) (*big.Int, error) {
+	data := balanceOfSelector + encodeAddress(holder)
+	results, err := c.batchCall(ctx, token, []string{data})
+	if err != nil {
+		return nil, err
+	}
+	balance, exist := results[data]
+	if !exist {
+		return nil, errors.New("balanceOf failed")
+	}
+	return new(big.Int).SetBytes(balance), nil
+}
+
+// VerifyPool checks the factory the pool reports returns the pool for its tokens and fee,
+// a contract can report any factory and tokens.
+func (c *Client) VerifyPool(ctx context.Context, address string, state PoolState) (bool, error) {
+	var data string
+	switch state.Kind {
+	case common.PoolKindV2:
+		data = getPairSelector + encodeAddress(state.Token0) + encodeAddress(state.Token1)
+	case common.PoolKindV3:
+		// fee is a uint24
+		if state.Fee < 0 || state.Fee >= 1<<24 {
+			return false, nil
+		}
+		data = getPoolSelector + encodeAddress(state.Token0) + encodeAddress(state.Token1) + fmt.Sprintf("%064x", state.Fee)
+	default:
+		return false, ErrNotPool
+	}
+	results, err := c.batchCall(ctx, state.Factory, []string{data})
+	if err != nil {
+		return false, err
+	}
+	pool := decodeAddress(results[data])
+	return pool != "" && pool == strings.ToLower(address), nil
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/breaker"
+)
+
+// Leadership reports whether the replica runs the leader only workers.
+type Leadership interface {
+	IsLeader() bool
+	// LeaderSince is when the leadership was acquired, zero if the replica is always leader.
+	LeaderSince() time.Time
+}
+
+type WorkerStatus struct {
+	Interval      time.Duration `json:"interval"`
+	LeaderOnly    bool          `json:"leaderOnly"`
+	LastSuccess   time.Time     `json:"lastSuccess"`
+	LastError     string        `json:"lastError,omitempty"`
+	LastErrorTime time.Time     `json:"lastErrorTime"`
+}
+
+type RedisStatus struct {
+	Connected bool   `json:"connected"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Status struct {
+	Ready    bool                     `json:"ready"`
+	Leader   bool                     `json:"leader"`
+	Workers  map[string]WorkerStatus  `json:"workers"`
+	Breakers map[string]breaker.State `json:"breakers"`
+	BlockLag map[common.Chain]int64   `json:"blockLag"`
+	Redis    RedisStatus              `json:"redis"`
+}
+
+// Tracker collects the outcome of worker cycles to answer readiness and status requests.
+// A worker is considered stuck when it didn't succeed within maxMissedCycles intervals.
+type Tracker struct {
+	mu              sync.RWMutex
+	started         time.Time
+	maxMissedCycles int
+	leadership      Leadership
+	redisPing       func(ctx context.Context) error
+	workers         map[string]*WorkerStatus
+	breakers        []*breaker.Breaker
+	blockLag        map[common.Chain]int64
+}
+
+func NewTracker(maxMissedCycles int, leadership Leadership, redisPing func(ctx context.Context) error) *Tracker {
+	return &Tracker{
+		started:         time.Now(),
+		maxMissedCycles: maxMissedCycles,
+		leadership:      leadership,
+		redisPing:       redisPing,
+		workers:         make(map[string]*WorkerStatus),
+		blockLag:        make(map[common.Chain]int64),
+	}
+}
+
+// RegisterWorker adds a worker to the readiness check, a leader only worker is ignored on standbys.
+func (t *Tracker) RegisterWorker(worker string, interval time.Duration, leaderOnly bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.workers[worker] = &WorkerStatus{
+		Interval:   interval,
+		LeaderOnly: leaderOnly,
+	}
+}
+
+func (t *Tracker) RegisterBreaker(b *breaker.Breaker) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.breakers = append(t.breakers, b)
+}
+
+func (t *Tracker) CycleSucceeded(worker string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if w, exist := t.workers[worker]; exist {
+		w.LastSuccess = time.Now()
+	}
+}
+
+func (t *Tracker) CycleFailed(worker string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if w, exist := t.workers[worker]; exist {
+		w.LastError = err.Error()
+		w.LastErrorTime = time.Now()
+	}
+}
+
+func (t *Tracker) SetBlockLag(chain common.Chain, lag int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.blockLag[chain] = lag
+}
+
+// Ready reports whether every worker running on this replica succeeded recently.
+func (t *Tracker) Ready() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	leader := t.leadership.IsLeader()
+	leaderSince := t.leadership.LeaderSince()
+	for _, w := range t.workers {
+		if w.LeaderOnly && !leader {
+			continue
+		}
+		// give the first cycle the same grace period, a leader only worker
+		// gets it again when the leadership is acquired
+		graceStart := t.started
+		if w.LeaderOnly && leaderSince.After(graceStart) {
+			graceStart = leaderSince
+		}
+		last := w.LastSuccess
+		if last.Before(graceStart) {
+			last = graceStart
+		}
+		if time.Since(last) > time.Duration(t.maxMissedCycles)*w.Interval {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Tracker) Status(ctx context.Context) Status {
+	redis := RedisStatus{Connected: true}
+	if err := t.redisPing(ctx); err != nil {
+		redis = RedisStatus{Connected: false, Error: err.Error()}
+	}
+	ready := t.Ready()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	status := Status{
+		Ready:    ready,
+		Leader:   t.leadership.IsLeader(),
+		Workers:  make(map[string]WorkerStatus, len(t.workers)),
+		Breakers: make(map[string]breaker.State, len(t.breakers)),
+		BlockLag: make(map[common.Chain]int64, len(t.blockLag)),
+		Redis:    redis,
+	}
+	for name, w := range t.workers {
+		status.Workers[name] = *w
+	}
+	for _, b := range t.breakers {
+		status.Breakers[b.Name()] = b.State()
+	}
+	for chain, lag := range t.blockLag {
+		status.BlockLag[chain] = lag
+	}
+	return status
+}
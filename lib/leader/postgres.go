@@ -0,0 +1,121 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// PostgresElector elects a leader between replicas with a postgres session advisory lock.
+// The lock is held by a dedicated connection, so it's released as soon as the leader dies.
+type PostgresElector struct {
+	log    *zap.SugaredLogger
+	db     *sqlx.DB
+	lockID int64
+	retry  time.Duration
+	conn   *sqlx.Conn
+	leader atomic.Bool
+
+	mu sync.Mutex
+	// ctx lives as long as the leadership, it's cancelled on standbys
+	ctx    context.Context
+	cancel context.CancelFunc
+	since  time.Time
+}
+
+func NewPostgresElector(log *zap.SugaredLogger, db *sqlx.DB, lockID int64, retry time.Duration) *PostgresElector {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return &PostgresElector{
+		log:    log.With("lock_id", lockID),
+		db:     db,
+		lockID: lockID,
+		retry:  retry,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (e *PostgresElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+func (e *PostgresElector) Context() context.Context {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ctx
+}
+
+func (e *PostgresElector) LeaderSince() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.since
+}
+
+func (e *PostgresElector) setLeader(leader bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if leader {
+		e.ctx, e.cancel = context.WithCancel(context.Background())
+		e.since = time.Now()
+	} else {
+		e.cancel()
+	}
+	e.leader.Store(leader)
+}
+
+func (e *PostgresElector) Run() {
+	ticker := time.NewTicker(e.retry)
+	for ; ; <-ticker.C {
+		if e.IsLeader() {
+			e.checkLock()
+		} else {
+			e.tryLock()
+		}
+	}
+}
+
+func (e *PostgresElector) tryLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.retry)
+	defer cancel()
+	if e.conn == nil {
+		conn, err := e.db.Connx(ctx)
+		if err != nil {
+			e.log.Errorw("error when get connection for leader lock", "err", err)
+			return
+		}
+		e.conn = conn
+	}
+	var locked bool
+	if err := e.conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", e.lockID); err != nil {
+		e.log.Errorw("error when try leader lock", "err", err)
+		e.release()
+		return
+	}
+	if locked {
+		e.log.Infow("became leader")
+		e.setLeader(true)
+	}
+}
+
+func (e *PostgresElector) checkLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.retry)
+	defer cancel()
+	if err := e.conn.PingContext(ctx); err != nil {
+		e.log.Errorw("lost leader lock connection", "err", err)
+		e.setLeader(false)
+		e.release()
+	}
+}
+
+func (e *PostgresElector) release() {
+	if e.conn == nil {
+		return
+	}
+	_ = e.conn.Close()
+	e.conn = nil
+}
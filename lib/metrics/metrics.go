@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "token_rate"
+
+const (
+	ProviderDexScreener    = "dexscreener"
+	ProviderCoinMarketCap  = "coinmarketcap"
+	ProviderKaivestBinance = "kaivest_binance"
+	ProviderCoinGecko      = "coingecko"
+	ProviderRPC            = "rpc"
+
+	// StatusError is the status of a request which didn't get a http response
+	StatusError = "error"
+)
+
+var (
+	CycleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cycle_duration_seconds",
+		Help:      "Duration of a worker cycle.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"worker"})
+
+	TokensPublished = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tokens_published",
+		Help:      "Number of tokens in the last published snapshot by source price.",
+	}, []string{"source_price"})
+
+	ProviderRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_requests_total",
+		Help:      "Requests sent to rate and info providers by status code.",
+	}, []string{"provider", "status"})
+
+	ProviderRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of requests sent to rate and info providers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	BatchesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "batches_dropped_total",
+		Help:      "Provider batches whose rates were lost because the request failed.",
+	}, []string{"provider"})
+
+	TokensFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pairs_filtered_total",
+		Help:      "Dex pairs skipped by each publishing threshold.",
+	}, []string{"threshold"})
+
+	TokenPools = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "token_pools",
+		Help:      "Number of tokens tracked for dex rates by chain.",
+	}, []string{"chain"})
+
+	BlockLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "block_lag",
+		Help:      "Blocks between the last stored block in db and the last block processed by the rate worker.",
+	}, []string{"chain"})
+
+	Reorgs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reorgs_total",
+		Help:      "Rewritten block ranges detected in the logs by chain.",
+	}, []string{"chain"})
+
+	ReorgDepth = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reorg_depth_blocks",
+		Help:      "Blocks rewound after a reorg.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"chain"})
+
+	CmcPagesFetched = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cmc_pages_fetched_total",
+		Help:      "CoinMarketCap listing pages fetched.",
+	})
+
+	CmcCreditsUsed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cmc_credits_used_total",
+		Help:      "CoinMarketCap credits reported as used by the listing requests.",
+	})
+)
+
+// ObserveProviderRequest records a provider request, statusCode is 0 if no response was received.
+func ObserveProviderRequest(provider string, statusCode int, start time.Time) {
+	status := StatusError
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	ProviderRequests.WithLabelValues(provider, status).Inc()
+	ProviderRequestDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+}
+
+// ObserveProviderCall records a request made through a client which doesn't expose the status code.
+func ObserveProviderCall(provider string, err error, start time.Time) {
+	status := "ok"
+	if err != nil {
+		status = StatusError
+	}
+	ProviderRequests.WithLabelValues(provider, status).Inc()
+	ProviderRequestDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+}
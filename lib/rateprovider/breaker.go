@@ -0,0 +1,28 @@
+package rateprovider
+
+import (
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/breaker"
+)
+
+// BreakerRateProvider fails fast while the provider keeps failing.
+type BreakerRateProvider struct {
+	provider RateProvider
+	breaker  *breaker.Breaker
+}
+
+func NewBreakerRateProvider(provider RateProvider, breaker *breaker.Breaker) *BreakerRateProvider {
+	return &BreakerRateProvider{
+		provider: provider,
+		breaker:  breaker,
+	}
+}
+
+func (b *BreakerRateProvider) GetPrices(tokenAddress string) (common.Pairs, error) {
+	if !b.breaker.Allow() {
+		return common.Pairs{}, breaker.ErrOpen
+	}
+	pairs, err := b.provider.GetPrices(tokenAddress)
+	b.breaker.Record(err)
+	return pairs, err
+}
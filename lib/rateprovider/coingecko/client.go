@@ -0,0 +1,67 @@
+package coingecko
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
+	"go.uber.org/zap"
+)
+
+type CoinGecko struct {
+	log    *zap.SugaredLogger
+	client *http.Client
+	key    string
+	url    string
+}
+
+// NewCoinGecko creates a coingecko client, the key is optional and sent as a demo api key.
+func NewCoinGecko(log *zap.SugaredLogger, key string, url string) *CoinGecko {
+	return &CoinGecko{
+		log:    log,
+		client: &http.Client{},
+		key:    key,
+		url:    url,
+	}
+}
+
+// GetCoinList gets every coin with its contract address on each platform.
+func (c *CoinGecko) GetCoinList() ([]common.CoinGeckoCoin, error) {
+	req, err := http.NewRequest("GET", c.url+"/coins/list?include_platform=true", nil)
+	if err != nil {
+		c.log.Errorw("error when make request", "err", err)
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.key != "" {
+		req.Header.Set("x-cg-demo-api-key", c.key)
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		metrics.ObserveProviderRequest(metrics.ProviderCoinGecko, 0, start)
+		c.log.Errorw("Error sending request to server", "err", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	metrics.ObserveProviderRequest(metrics.ProviderCoinGecko, resp.StatusCode, start)
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.log.Errorw("Error sending read resp body", "err", err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("coingecko status %d: %s", resp.StatusCode, respBody)
+	}
+	var coins []common.CoinGeckoCoin
+	if err := json.Unmarshal(respBody, &coins); err != nil {
+		c.log.Errorw("Error sending parse to coingecko", "err", err)
+		return nil, err
+	}
+	return coins, nil
+}
@@ -2,15 +2,39 @@ package coinmarketcap
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
 	"go.uber.org/zap"
 )
 
+// APIError is a response CoinMarketCap rejected, the status is the one of the response body.
+type APIError struct {
+	StatusCode int
+	Status     common.CoinMarketCapStatus
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("coinmarketcap status %d, error code %d: %s", e.StatusCode, e.Status.ErrorCode, e.Status.ErrorMessage)
+}
+
+// Retryable reports if a request which failed with err may succeed later, rejected keys and
+// exhausted credits won't.
+func Retryable(err error) bool {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return true
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+}
+
 type CoinMarketCap struct {
 	log    *zap.SugaredLogger
 	client *http.Client
@@ -27,37 +51,71 @@ func NewCoinMarketCap(log *zap.SugaredLogger, key string, url string) *CoinMarke
 	}
 }
 
-func (c *CoinMarketCap) GetTokenInfo(start, limit int64) (common.CoinMarketCapTokenInfo, error) {
-	path := c.url + "/v1/cryptocurrency/listings/latest"
-	req, err := http.NewRequest("GET", path, nil)
+func (c *CoinMarketCap) get(path string, q url.Values, dest interface{}) error {
+	req, err := http.NewRequest("GET", c.url+path, nil)
 	if err != nil {
 		c.log.Errorw("error when make request", "err", err)
-		return common.CoinMarketCapTokenInfo{}, err
+		return err
 	}
 
-	q := url.Values{}
-	q.Add("start", strconv.FormatInt(start, 10))
-	q.Add("limit", strconv.FormatInt(limit, 10))
-
 	req.Header.Set("Accepts", "application/json")
 	req.Header.Add("X-CMC_PRO_API_KEY", c.key)
 	req.URL.RawQuery = q.Encode()
 
+	requestTime := time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
+		metrics.ObserveProviderRequest(metrics.ProviderCoinMarketCap, 0, requestTime)
 		c.log.Errorw("Error sending request to server", "err", err)
-		return common.CoinMarketCapTokenInfo{}, err
+		return err
 	}
+	defer resp.Body.Close()
+	metrics.ObserveProviderRequest(metrics.ProviderCoinMarketCap, resp.StatusCode, requestTime)
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		c.log.Errorw("Error sending read resp body", "err", err)
-		return common.CoinMarketCapTokenInfo{}, err
+		return err
 	}
-	var coinMarketCap common.CoinMarketCapTokenInfo
-	if err := json.Unmarshal(respBody, &coinMarketCap); err != nil {
+	if resp.StatusCode != http.StatusOK {
+		var status struct {
+			Status common.CoinMarketCapStatus `json:"status"`
+		}
+		_ = json.Unmarshal(respBody, &status)
+		return &APIError{StatusCode: resp.StatusCode, Status: status.Status}
+	}
+	if err := json.Unmarshal(respBody, dest); err != nil {
 		c.log.Errorw("Error sending parse to coin market cap", "err", err)
-		return common.CoinMarketCapTokenInfo{}, err
+		return err
 	}
+	return nil
+}
+
+func (c *CoinMarketCap) GetTokenInfo(start, limit int64) (common.CoinMarketCapTokenInfo, error) {
+	q := url.Values{}
+	q.Add("start", strconv.FormatInt(start, 10))
+	q.Add("limit", strconv.FormatInt(limit, 10))
 
+	var coinMarketCap common.CoinMarketCapTokenInfo
+	if err := c.get("/v1/cryptocurrency/listings/latest", q, &coinMarketCap); err != nil {
+		return common.CoinMarketCapTokenInfo{}, err
+	}
 	return coinMarketCap, nil
 }
+
+// GetTokenMetadata gets the logo, urls and contract addresses of the tokens with the ids.
+func (c *CoinMarketCap) GetTokenMetadata(ids []int) (common.CoinMarketCapMetadataInfo, error) {
+	strIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strIDs = append(strIDs, strconv.Itoa(id))
+	}
+	q := url.Values{}
+	q.Add("id", strings.Join(strIDs, ","))
+	q.Add("aux", "urls,logo,platform")
+	q.Add("skip_invalid", "true")
+
+	var info common.CoinMarketCapMetadataInfo
+	if err := c.get("/v2/cryptocurrency/info", q, &info); err != nil {
+		return common.CoinMarketCapMetadataInfo{}, err
+	}
+	return info, nil
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
 	"github.com/kv-base-hack/common/utils"
 	"go.uber.org/zap"
 )
@@ -34,11 +36,15 @@ func (d *DexScreener) GetPrices(tokenAddress string) (common.Pairs, error) {
 		return common.Pairs{}, err
 	}
 
+	start := time.Now()
 	resp, err := d.client.Do(req)
 	if err != nil {
+		metrics.ObserveProviderRequest(metrics.ProviderDexScreener, 0, start)
 		log.Errorw("Error sending request to server", "err", err)
 		return common.Pairs{}, err
 	}
+	defer resp.Body.Close()
+	metrics.ObserveProviderRequest(metrics.ProviderDexScreener, resp.StatusCode, start)
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Errorw("Error sending read resp body", "err", err)
@@ -0,0 +1,22 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS price_audit_log
+(
+    id            BIGSERIAL PRIMARY KEY,
+    token_address TEXT             NOT NULL,
+    chain_id      TEXT             NOT NULL,
+    symbol        TEXT             NOT NULL,
+    usd_price     DOUBLE PRECISION NOT NULL,
+    source_price  TEXT             NOT NULL,
+    dex_id        TEXT             NOT NULL DEFAULT '',
+    url           TEXT             NOT NULL DEFAULT '',
+    pair_address  TEXT             NOT NULL DEFAULT '',
+    liquidity_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    volume_h24    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rejected      JSONB            NOT NULL DEFAULT '[]',
+    published_at  TIMESTAMPTZ      NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS price_audit_log_token_published_at_idx ON price_audit_log (token_address, published_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS price_audit_log;
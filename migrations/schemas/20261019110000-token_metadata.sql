@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS token_metadata
+(
+    chain_id         TEXT        NOT NULL,
+    address          TEXT        NOT NULL,
+    name             TEXT        NOT NULL DEFAULT '',
+    symbol           TEXT        NOT NULL DEFAULT '',
+    decimals         INT,
+    total_supply     DOUBLE PRECISION,
+    first_seen_block BIGINT,
+    first_minter     TEXT        NOT NULL DEFAULT '',
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain_id, address)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS token_metadata;
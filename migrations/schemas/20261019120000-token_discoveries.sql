@@ -0,0 +1,20 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS token_discoveries
+(
+    chain_id              TEXT        NOT NULL,
+    address               TEXT        NOT NULL,
+    first_seen_block      BIGINT      NOT NULL,
+    first_seen_at         TIMESTAMPTZ NOT NULL,
+    first_trade_block     BIGINT,
+    first_minter          TEXT        NOT NULL DEFAULT '',
+    initial_price_usd     DOUBLE PRECISION,
+    initial_liquidity_usd DOUBLE PRECISION,
+    priced_at             TIMESTAMPTZ,
+    PRIMARY KEY (chain_id, address)
+);
+
+-- the pages are keyed by (first_seen_at, address), a batch of discoveries shares its first_seen_at
+CREATE INDEX IF NOT EXISTS token_discoveries_cursor_idx ON token_discoveries (chain_id, first_seen_at DESC, address DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS token_discoveries;
@@ -0,0 +1,35 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS pools
+(
+    chain_id         TEXT        NOT NULL,
+    address          TEXT        NOT NULL,
+    kind             TEXT        NOT NULL,
+    factory          TEXT        NOT NULL DEFAULT '',
+    token0           TEXT        NOT NULL,
+    token1           TEXT        NOT NULL,
+    fee              BIGINT      NOT NULL DEFAULT 0,
+    first_seen_block BIGINT      NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain_id, address)
+);
+
+CREATE INDEX IF NOT EXISTS pools_token0_idx ON pools (chain_id, token0);
+CREATE INDEX IF NOT EXISTS pools_token1_idx ON pools (chain_id, token1);
+
+CREATE TABLE IF NOT EXISTS pool_reserves
+(
+    id             BIGSERIAL PRIMARY KEY,
+    pool_address   TEXT             NOT NULL,
+    block_number   BIGINT           NOT NULL,
+    reserve0       DOUBLE PRECISION NOT NULL,
+    reserve1       DOUBLE PRECISION NOT NULL,
+    sqrt_price_x96 TEXT             NOT NULL DEFAULT '',
+    price0         DOUBLE PRECISION NOT NULL,
+    observed_at    TIMESTAMPTZ      NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS pool_reserves_pool_observed_at_idx ON pool_reserves (pool_address, observed_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS pool_reserves;
+DROP TABLE IF EXISTS pools;
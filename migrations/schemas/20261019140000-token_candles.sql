@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS token_candles
+(
+    chain_id        TEXT             NOT NULL,
+    token_address   TEXT             NOT NULL,
+    interval_blocks BIGINT           NOT NULL,
+    start_block     BIGINT           NOT NULL,
+    open            DOUBLE PRECISION NOT NULL,
+    high            DOUBLE PRECISION NOT NULL,
+    low             DOUBLE PRECISION NOT NULL,
+    close           DOUBLE PRECISION NOT NULL,
+    volume_usd      DOUBLE PRECISION NOT NULL,
+    trades          BIGINT           NOT NULL,
+    PRIMARY KEY (chain_id, token_address, interval_blocks, start_block)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS token_candles;
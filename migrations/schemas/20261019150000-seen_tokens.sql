@@ -0,0 +1,26 @@
+-- +migrate Up notransaction
+CREATE TABLE IF NOT EXISTS seen_tokens
+(
+    chain_id         TEXT   NOT NULL,
+    address          TEXT   NOT NULL,
+    first_seen_block BIGINT NOT NULL,
+    last_seen_block  BIGINT NOT NULL,
+    PRIMARY KEY (chain_id, address)
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS seen_tokens_last_seen_idx ON seen_tokens (chain_id, last_seen_block, address);
+
+-- the range scans of the logs and the lookups by token
+CREATE INDEX CONCURRENTLY IF NOT EXISTS base_trade_logs_block_number_idx ON base_trade_logs (block_number);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS base_trade_logs_token_in_idx ON base_trade_logs (LOWER(token_in_address), block_number);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS base_trade_logs_token_out_idx ON base_trade_logs (LOWER(token_out_address), block_number);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS base_transfer_logs_block_number_idx ON base_transfer_logs (block_number);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS base_transfer_logs_token_idx ON base_transfer_logs (LOWER(token_address), block_number);
+
+-- +migrate Down notransaction
+DROP INDEX CONCURRENTLY IF EXISTS base_transfer_logs_token_idx;
+DROP INDEX CONCURRENTLY IF EXISTS base_transfer_logs_block_number_idx;
+DROP INDEX CONCURRENTLY IF EXISTS base_trade_logs_token_out_idx;
+DROP INDEX CONCURRENTLY IF EXISTS base_trade_logs_token_in_idx;
+DROP INDEX CONCURRENTLY IF EXISTS base_trade_logs_block_number_idx;
+DROP TABLE IF EXISTS seen_tokens;
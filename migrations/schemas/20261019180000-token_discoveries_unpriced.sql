@@ -0,0 +1,6 @@
+-- +migrate Up
+-- the unpriced discoveries are reloaded every full cycle
+CREATE INDEX IF NOT EXISTS token_discoveries_unpriced_idx ON token_discoveries (chain_id, first_seen_at) WHERE priced_at IS NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS token_discoveries_unpriced_idx;
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// AuditLog is the append only history of published prices.
+type AuditLog interface {
+	InsertPriceAudits(ctx context.Context, audits []common.PriceAudit) error
+	GetPriceAudits(ctx context.Context, tokenAddress string, from, to time.Time) ([]common.PriceAudit, error)
+}
+
+func (pg *Postgres) InsertPriceAudits(ctx context.Context, audits []common.PriceAudit) error {
+	if len(audits) == 0 {
+		return nil
+	}
+	insert := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert(PriceAuditLog).
+		Columns("token_address", "chain_id", "symbol", "usd_price", "source_price", "dex_id", "url", "pair_address",
+			"liquidity_usd", "volume_h24", "rejected", "published_at")
+	for _, a := range audits {
+		insert = insert.Values(strings.ToLower(a.TokenAddress), a.ChainID, a.Symbol, a.UsdPrice, a.SourcePrice, a.DexID, a.Url, a.PairAddress,
+			a.LiquidityUsd, a.VolumeH24, a.Rejected, a.PublishedAt)
+	}
+	query, args, err := insert.ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "InsertPriceAudits", query, args...)
+	return err
+}
+
+func (pg *Postgres) GetPriceAudits(ctx context.Context, tokenAddress string, from, to time.Time) ([]common.PriceAudit, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("token_address", "chain_id", "symbol", "usd_price", "source_price", "dex_id", "url", "pair_address",
+			"liquidity_usd", "volume_h24", "rejected", "published_at").
+		From(PriceAuditLog).
+		Where(sq.And{
+			sq.Eq{"token_address": strings.ToLower(tokenAddress)},
+			sq.GtOrEq{"published_at": from},
+			sq.LtOrEq{"published_at": to},
+		}).
+		OrderBy("published_at").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []common.PriceAudit
+	err = pg.selectRows(ctx, "GetPriceAudits", &result, query, args...)
+	return result, err
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// CandleStore reads the trades against the anchors and stores the candles built from them.
+type CandleStore interface {
+	// GetAnchorTradesByRange returns the trades of the block range with an anchor on a side, ordered by block.
+	// The logs have no log index, the trades of a block are in a stable but arbitrary order.
+	GetAnchorTradesByRange(ctx context.Context, table string, from, to int64, anchors []string) ([]common.Trade, error)
+	SaveCandles(ctx context.Context, candles []common.Candle) error
+	GetCandles(ctx context.Context, chainID, tokenAddress string, intervalBlocks, from, to int64) ([]common.Candle, error)
+}
+
+var candleColumns = []string{"chain_id", "token_address", "interval_blocks", "start_block", "open", "high", "low", "close",
+	"volume_usd", "trades"}
+
+// candles are saved in batches under the postgres parameter limit
+const candleBatch = 5000
+
+func (pg *Postgres) GetAnchorTradesByRange(ctx context.Context, table string, from, to int64, anchors []string) ([]common.Trade, error) {
+	lower := lowerAll(anchors)
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("block_number", "LOWER(token_in_address) AS token_in_address", "LOWER(token_out_address) AS token_out_address",
+			"amount_in", "amount_out").
+		From(table).
+		Where(sq.And{
+			sq.GtOrEq{"block_number": from},
+			sq.LtOrEq{"block_number": to},
+			sq.Or{sq.Eq{"LOWER(token_in_address)": lower}, sq.Eq{"LOWER(token_out_address)": lower}},
+		}).
+		// the same trades always give the same open and close of a candle
+		OrderBy("block_number", "pool_address", "token_in_address", "token_out_address", "amount_in", "amount_out").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []common.Trade
+	err = pg.selectRows(ctx, "GetAnchorTradesByRange", &result, query, args...)
+	return result, err
+}
+
+func (pg *Postgres) SaveCandles(ctx context.Context, candles []common.Candle) error {
+	for bg := 0; bg < len(candles); bg += candleBatch {
+		end := bg + candleBatch
+		if end > len(candles) {
+			end = len(candles)
+		}
+		insert := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+			Insert(TokenCandles).Columns(candleColumns...)
+		for _, c := range candles[bg:end] {
+			insert = insert.Values(c.ChainID, c.TokenAddress, c.IntervalBlocks, c.StartBlock, c.Open, c.High, c.Low, c.Close,
+				c.VolumeUsd, c.Trades)
+		}
+		query, args, err := insert.Suffix("ON CONFLICT (chain_id, token_address, interval_blocks, start_block) DO UPDATE SET " +
+			"open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low, close = EXCLUDED.close, " +
+			"volume_usd = EXCLUDED.volume_usd, trades = EXCLUDED.trades").ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := pg.exec(ctx, "SaveCandles", query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pg *Postgres) GetCandles(ctx context.Context, chainID, tokenAddress string, intervalBlocks, from, to int64) ([]common.Candle, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select(candleColumns...).From(TokenCandles).
+		Where(sq.And{
+			sq.Eq{"chain_id": chainID, "token_address": strings.ToLower(tokenAddress), "interval_blocks": intervalBlocks},
+			sq.GtOrEq{"start_block": from},
+			sq.LtOrEq{"start_block": to},
+		}).
+		OrderBy("start_block").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []common.Candle
+	err = pg.selectRows(ctx, "GetCandles", &result, query, args...)
+	return result, err
+}
@@ -0,0 +1,320 @@
+// Package dbtest checks that an implementation of the db.DB interface behaves like the others.
+package dbtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+)
+
+// Backend is a DB the suite can feed with logs.
+type Backend interface {
+	db.DB
+	db.LogWriter
+}
+
+type check struct {
+	name string
+	run  func(ctx context.Context, b Backend) error
+}
+
+var checks = []check{
+	{"last stored block", checkLastStoredBlock},
+	{"unique token address for trade", checkUniqueTrade},
+	{"unique token address for transfer", checkUniqueTransfer},
+	{"trade count", checkTradeCount},
+	{"seen tokens", checkSeenTokens},
+	{"seen tokens paging", checkSeenTokensPaging},
+	{"worker state", checkWorkerState},
+	{"worker sub states", checkWorkerStates},
+}
+
+// TestDB runs every check against a fresh backend from newBackend and returns the failures joined,
+// nil if the backend conforms. The backend is closed after the check if it implements io.Closer.
+func TestDB(ctx context.Context, newBackend func() (Backend, error)) error {
+	var errs []error
+	for _, c := range checks {
+		b, err := newBackend()
+		if err != nil {
+			return fmt.Errorf("new backend: %w", err)
+		}
+		if err := c.run(ctx, b); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+		if closer, ok := b.(interface{ Close() error }); ok {
+			_ = closer.Close()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+const (
+	tokenA = "0x000000000000000000000000000000000000000A"
+	tokenB = "0x000000000000000000000000000000000000000b"
+	tokenC = "0x000000000000000000000000000000000000000c"
+	tokenD = "0x000000000000000000000000000000000000000d"
+)
+
+var tradeLogs = []common.TradeLog{
+	{BlockNumber: 10, BlockHash: "0x10", TokenInAddress: tokenA, TokenOutAddress: tokenB, AmountIn: 1, AmountOut: 2},
+	{BlockNumber: 11, BlockHash: "0x11", TokenInAddress: tokenB, TokenOutAddress: tokenA, AmountIn: 2, AmountOut: 1},
+	{BlockNumber: 12, BlockHash: "0x12", TokenInAddress: tokenA, TokenOutAddress: tokenC, AmountIn: 1, AmountOut: 3},
+	{BlockNumber: 20, BlockHash: "0x20", TokenInAddress: tokenC, TokenOutAddress: tokenB, AmountIn: 3, AmountOut: 2},
+}
+
+var transferLogs = []common.TransferLog{
+	{BlockNumber: 9, BlockHash: "0x09", TokenAddress: tokenD, FromAddress: "0x0", ToAddress: "0x1", Amount: 100},
+	{BlockNumber: 12, BlockHash: "0x12", TokenAddress: tokenA, FromAddress: "0x1", ToAddress: "0x2", Amount: 1},
+	{BlockNumber: 25, BlockHash: "0x25", TokenAddress: tokenD, FromAddress: "0x1", ToAddress: "0x2", Amount: 5},
+}
+
+func insertLogs(ctx context.Context, b Backend) error {
+	if err := b.InsertTradeLogs(ctx, db.BaseTradeLogs, tradeLogs); err != nil {
+		return fmt.Errorf("insert trade logs: %w", err)
+	}
+	if err := b.InsertTransferLogs(ctx, db.BaseTransferLogs, transferLogs); err != nil {
+		return fmt.Errorf("insert transfer logs: %w", err)
+	}
+	return nil
+}
+
+func sorted(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	return result
+}
+
+func expectEqual(what string, got, want interface{}) error {
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%s: got %v, want %v", what, got, want)
+	}
+	return nil
+}
+
+func checkLastStoredBlock(ctx context.Context, b Backend) error {
+	last, err := b.GetLastStoredBlock(ctx, db.BaseTradeLogs)
+	if err != nil {
+		return err
+	}
+	if err := expectEqual("empty table", last, int64(0)); err != nil {
+		return err
+	}
+	if err := insertLogs(ctx, b); err != nil {
+		return err
+	}
+	last, err = b.GetLastStoredBlock(ctx, db.BaseTradeLogs)
+	if err != nil {
+		return err
+	}
+	return expectEqual("last trade block", last, int64(20))
+}
+
+func checkUniqueTrade(ctx context.Context, b Backend) error {
+	if err := insertLogs(ctx, b); err != nil {
+		return err
+	}
+	addresses, err := b.GetUniqueTokenAddressByRangeForTrade(ctx, db.BaseTradeLogs, 10, 12)
+	if err != nil {
+		return err
+	}
+	if err := expectEqual("tokens of 10-12", sorted(addresses), sorted([]string{tokenA, tokenB, tokenC})); err != nil {
+		return err
+	}
+	addresses, err = b.GetUniqueTokenAddressByRangeForTrade(ctx, db.BaseTradeLogs, 13, 19)
+	if err != nil {
+		return err
+	}
+	return expectEqual("tokens of 13-19", len(addresses), 0)
+}
+
+func checkUniqueTransfer(ctx context.Context, b Backend) error {
+	if err := insertLogs(ctx, b); err != nil {
+		return err
+	}
+	addresses, err := b.GetUniqueTokenAddressByRangeForTransfer(ctx, db.BaseTransferLogs, 0, 30)
+	if err != nil {
+		return err
+	}
+	return expectEqual("tokens of 0-30", sorted(addresses), sorted([]string{tokenA, tokenD}))
+}
+
+func checkTradeCount(ctx context.Context, b Backend) error {
+	if err := insertLogs(ctx, b); err != nil {
+		return err
+	}
+	counts, err := b.GetTradeCountByRange(ctx, db.BaseTradeLogs, 10, 12)
+	if err != nil {
+		return err
+	}
+	return expectEqual("trades of 10-12", counts, map[string]int64{tokenA: 3, tokenB: 2, tokenC: 1})
+}
+
+func seenTokens(ctx context.Context, b Backend, from, to int64) (map[string]common.SeenToken, error) {
+	page, err := b.GetSeenTokensByRange(ctx, common.ChainBase.String(), from, to, common.SeenTokenCursor{}, 100)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]common.SeenToken, len(page))
+	for _, t := range page {
+		result[t.Address] = t
+	}
+	return result, nil
+}
+
+func seen(address string, first, last int64) common.SeenToken {
+	return common.SeenToken{ChainID: common.ChainBase.String(), Address: address, FirstSeenBlock: first, LastSeenBlock: last}
+}
+
+func checkSeenTokens(ctx context.Context, b Backend) error {
+	chainID := common.ChainBase.String()
+	if err := insertLogs(ctx, b); err != nil {
+		return err
+	}
+	if _, err := b.UpdateSeenTokens(ctx, chainID, db.BaseTradeLogs, db.BaseTransferLogs, 0, 12); err != nil {
+		return err
+	}
+	got, err := seenTokens(ctx, b, 0, 30)
+	if err != nil {
+		return err
+	}
+	// addresses are lowercase and the ranges cover trades and transfers
+	want := map[string]common.SeenToken{
+		"0x000000000000000000000000000000000000000a": seen("0x000000000000000000000000000000000000000a", 10, 12),
+		tokenB: seen(tokenB, 10, 11),
+		tokenC: seen(tokenC, 12, 12),
+		tokenD: seen(tokenD, 9, 9),
+	}
+	if err := expectEqual("seen tokens of 0-12", got, want); err != nil {
+		return err
+	}
+
+	updated, err := b.UpdateSeenTokens(ctx, chainID, db.BaseTradeLogs, db.BaseTransferLogs, 13, 30)
+	if err != nil {
+		return err
+	}
+	if err := expectEqual("updated tokens of 13-30", updated, int64(3)); err != nil {
+		return err
+	}
+	// a rescan of an older range keeps the widest range
+	if _, err := b.UpdateSeenTokens(ctx, chainID, db.BaseTradeLogs, db.BaseTransferLogs, 11, 11); err != nil {
+		return err
+	}
+	got, err = seenTokens(ctx, b, 13, 30)
+	if err != nil {
+		return err
+	}
+	want = map[string]common.SeenToken{
+		tokenB: seen(tokenB, 10, 20),
+		tokenC: seen(tokenC, 12, 20),
+		tokenD: seen(tokenD, 9, 25),
+	}
+	return expectEqual("seen tokens of 13-30", got, want)
+}
+
+func checkSeenTokensPaging(ctx context.Context, b Backend) error {
+	chainID := common.ChainBase.String()
+	if err := insertLogs(ctx, b); err != nil {
+		return err
+	}
+	if _, err := b.UpdateSeenTokens(ctx, chainID, db.BaseTradeLogs, db.BaseTransferLogs, 0, 30); err != nil {
+		return err
+	}
+	var got []common.SeenToken
+	var cursor common.SeenTokenCursor
+	for pages := 0; ; pages++ {
+		if pages > 4 {
+			return errors.New("paging doesn't end")
+		}
+		page, err := b.GetSeenTokensByRange(ctx, chainID, 0, 30, cursor, 2)
+		if err != nil {
+			return err
+		}
+		got = append(got, page...)
+		if len(page) < 2 {
+			break
+		}
+		last := page[len(page)-1]
+		cursor = common.SeenTokenCursor{LastSeenBlock: last.LastSeenBlock, Address: last.Address}
+	}
+	want := []common.SeenToken{
+		seen("0x000000000000000000000000000000000000000a", 10, 12),
+		seen(tokenB, 10, 20),
+		seen(tokenC, 12, 20),
+		seen(tokenD, 9, 25),
+	}
+	return expectEqual("pages ordered by last seen block and address", got, want)
+}
+
+func jsonEqual(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func checkWorkerState(ctx context.Context, b Backend) error {
+	const name = "dbtest"
+	state, err := b.GetWorkerState(ctx, name)
+	if err != nil {
+		return err
+	}
+	if state != nil {
+		return fmt.Errorf("missing state: got %s, want nil", state)
+	}
+	for _, want := range []string{`{"lastStoredBlock": 10}`, `{"lastStoredBlock": 20, "tokenPools": {"0xa": 1}}`} {
+		if err := b.SaveWorkerState(ctx, name, []byte(want)); err != nil {
+			return err
+		}
+		state, err = b.GetWorkerState(ctx, name)
+		if err != nil {
+			return err
+		}
+		// postgres stores the state as jsonb, which doesn't keep the formatting
+		if !jsonEqual(state, []byte(want)) {
+			return fmt.Errorf("saved state: got %s, want %s", state, want)
+		}
+	}
+	if err := b.DeleteWorkerState(ctx, name); err != nil {
+		return err
+	}
+	state, err = b.GetWorkerState(ctx, name)
+	if err != nil {
+		return err
+	}
+	if state != nil {
+		return fmt.Errorf("deleted state: got %s, want nil", state)
+	}
+	return nil
+}
+
+func checkWorkerStates(ctx context.Context, b Backend) error {
+	for _, name := range []string{"worker", "worker:1", "worker:2", "worker_other", "workers:1"} {
+		if err := b.SaveWorkerState(ctx, name, []byte(`{}`)); err != nil {
+			return err
+		}
+	}
+	deleted, err := b.DeleteWorkerStates(ctx, "worker")
+	if err != nil {
+		return err
+	}
+	if deleted != 3 {
+		return fmt.Errorf("deleted states: got %d, want 3", deleted)
+	}
+	for name, kept := range map[string]bool{"worker": false, "worker:1": false, "worker:2": false, "worker_other": true, "workers:1": true} {
+		state, err := b.GetWorkerState(ctx, name)
+		if err != nil {
+			return err
+		}
+		if (state != nil) != kept {
+			return fmt.Errorf("state %s: kept %v, want %v", name, state != nil, kept)
+		}
+	}
+	return nil
+}
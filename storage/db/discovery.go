@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// DiscoveryFeed stores the tokens seen for the first time.
+type DiscoveryFeed interface {
+	GetTokenFirstSeen(ctx context.Context, table string, addresses []string) (map[string]common.TokenFirstSeen, error)
+	GetTokenFirstTrade(ctx context.Context, table string, addresses []string) (map[string]int64, error)
+	// InsertTokenDiscoveries returns the discoveries which weren't stored yet.
+	InsertTokenDiscoveries(ctx context.Context, discoveries []common.TokenDiscovery) ([]common.TokenDiscovery, error)
+	// SetTokenDiscoveryPriced sets the first price of a discovery, it returns nil if it was already priced.
+	SetTokenDiscoveryPriced(ctx context.Context, chainID, address string, priceUsd, liquidityUsd float64, at time.Time) (*common.TokenDiscovery, error)
+	// GetTokenDiscoveries returns the discoveries after the cursor, the newest first. The discoveries of a
+	// batch share their first seen time, they are ordered by address.
+	GetTokenDiscoveries(ctx context.Context, chainID string, after common.TokenDiscoveryCursor, limit uint64) ([]common.TokenDiscovery, error)
+	// GetUnpricedTokenDiscoveries returns the addresses of the discoveries seen since a time without a first price.
+	GetUnpricedTokenDiscoveries(ctx context.Context, chainID string, since time.Time) ([]string, error)
+}
+
+var tokenDiscoveryColumns = []string{"chain_id", "address", "first_seen_block", "first_seen_at", "first_trade_block", "first_minter",
+	"initial_price_usd", "initial_liquidity_usd", "priced_at"}
+
+type tokenFirstTrade struct {
+	TokenAddress string `db:"token_address"`
+	Block        int64  `db:"block_number"`
+}
+
+func (pg *Postgres) GetTokenFirstTrade(ctx context.Context, table string, addresses []string) (map[string]int64, error) {
+	result := make(map[string]int64, len(addresses))
+	if len(addresses) == 0 {
+		return result, nil
+	}
+	lower := lowerAll(addresses)
+	sql, args, _ := sq.Select("LOWER(token_out_address) AS token_address", "block_number").From(table).
+		Where(sq.Eq{"LOWER(token_out_address)": lower}).ToSql()
+	trades := sq.Select("LOWER(token_in_address) AS token_address", "block_number").From(table).
+		Where(sq.Eq{"LOWER(token_in_address)": lower}).
+		Suffix("UNION ALL "+sql, args...)
+	q, p, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("token_address", "MIN(block_number) AS block_number").
+		FromSelect(trades, "trades").
+		GroupBy("token_address").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []tokenFirstTrade
+	if err := pg.selectRows(ctx, "GetTokenFirstTrade", &rows, q, p...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.TokenAddress] = r.Block
+	}
+	return result, nil
+}
+
+func (pg *Postgres) InsertTokenDiscoveries(ctx context.Context, discoveries []common.TokenDiscovery) ([]common.TokenDiscovery, error) {
+	if len(discoveries) == 0 {
+		return nil, nil
+	}
+	insert := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert(TokenDiscoveries).Columns(tokenDiscoveryColumns...)
+	for _, d := range discoveries {
+		insert = insert.Values(d.ChainID, d.Address, d.FirstSeenBlock, d.FirstSeenAt, d.FirstTradeBlock, d.FirstMinter,
+			d.InitialPriceUsd, d.InitialLiquidityUsd, d.PricedAt)
+	}
+	query, args, err := insert.Suffix("ON CONFLICT (chain_id, address) DO NOTHING RETURNING address").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var inserted []string
+	if err := pg.selectRows(ctx, "InsertTokenDiscoveries", &inserted, query, args...); err != nil {
+		return nil, err
+	}
+	isInserted := make(map[string]bool, len(inserted))
+	for _, a := range inserted {
+		isInserted[a] = true
+	}
+	result := make([]common.TokenDiscovery, 0, len(inserted))
+	for _, d := range discoveries {
+		if isInserted[d.Address] {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+func (pg *Postgres) SetTokenDiscoveryPriced(ctx context.Context, chainID, address string, priceUsd, liquidityUsd float64,
+	at time.Time) (*common.TokenDiscovery, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Update(TokenDiscoveries).
+		Set("initial_price_usd", priceUsd).
+		Set("initial_liquidity_usd", liquidityUsd).
+		Set("priced_at", at).
+		Where(sq.Eq{"chain_id": chainID, "address": address, "priced_at": nil}).
+		Suffix("RETURNING " + joinColumns(tokenDiscoveryColumns)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var discovery common.TokenDiscovery
+	err = pg.get(ctx, "SetTokenDiscoveryPriced", &discovery, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &discovery, nil
+}
+
+func (pg *Postgres) GetTokenDiscoveries(ctx context.Context, chainID string, after common.TokenDiscoveryCursor,
+	limit uint64) ([]common.TokenDiscovery, error) {
+	where := sq.And{sq.Eq{"chain_id": chainID}}
+	if after != (common.TokenDiscoveryCursor{}) {
+		where = append(where, sq.Expr("(first_seen_at, address) < (?, ?)", after.FirstSeenAt, after.Address))
+	}
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select(tokenDiscoveryColumns...).From(TokenDiscoveries).
+		Where(where).
+		OrderBy("first_seen_at DESC", "address DESC").
+		Limit(limit).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []common.TokenDiscovery
+	err = pg.selectRows(ctx, "GetTokenDiscoveries", &result, query, args...)
+	return result, err
+}
+
+func (pg *Postgres) GetUnpricedTokenDiscoveries(ctx context.Context, chainID string, since time.Time) ([]string, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("address").From(TokenDiscoveries).
+		Where(sq.And{
+			sq.Eq{"chain_id": chainID, "priced_at": nil},
+			sq.GtOrEq{"first_seen_at": since},
+		}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	err = pg.selectRows(ctx, "GetUnpricedTokenDiscoveries", &result, query, args...)
+	return result, err
+}
@@ -1,7 +1,36 @@
 package db
 
+import (
+	"context"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
 type DB interface {
-	GetLastStoredBlock(table string) (int64, error)
-	GetUniqueTokenAddressByRangeForTrade(table string, from, to int64) ([]string, error)
-	GetUniqueTokenAddressByRangeForTransfer(table string, from, to int64) ([]string, error)
+	GetLastStoredBlock(ctx context.Context, table string) (int64, error)
+	GetUniqueTokenAddressByRangeForTrade(ctx context.Context, table string, from, to int64) ([]string, error)
+	GetUniqueTokenAddressByRangeForTransfer(ctx context.Context, table string, from, to int64) ([]string, error)
+	GetTradeCountByRange(ctx context.Context, table string, from, to int64) (map[string]int64, error)
+
+	// UpdateSeenTokens upserts the tokens of the trade and transfer logs in the block range into the seen tokens,
+	// it returns the number of tokens inserted or updated.
+	UpdateSeenTokens(ctx context.Context, chainID, tradeTable, transferTable string, from, to int64) (int64, error)
+	// GetSeenTokensByRange returns a page of the tokens last seen in the block range after the cursor,
+	// ordered by last seen block and address.
+	GetSeenTokensByRange(ctx context.Context, chainID string, from, to int64, after common.SeenTokenCursor, limit uint64) ([]common.SeenToken, error)
+
+	// GetWorkerState returns nil if no state is stored for the worker.
+	GetWorkerState(ctx context.Context, name string) ([]byte, error)
+	SaveWorkerState(ctx context.Context, name string, state []byte) error
+	DeleteWorkerState(ctx context.Context, name string) error
+	// DeleteWorkerStates deletes the state of the worker and its sub states named name:<id>,
+	// it returns the number of states deleted.
+	DeleteWorkerStates(ctx context.Context, name string) (int64, error)
+}
+
+// LogWriter appends trade and transfer logs. The indexer writes them in production, the local backends
+// and the conformance suite take them from here.
+type LogWriter interface {
+	InsertTradeLogs(ctx context.Context, table string, logs []common.TradeLog) error
+	InsertTransferLogs(ctx context.Context, table string, logs []common.TransferLog) error
 }
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+var (
+	tradeLogColumns    = []string{"block_number", "block_hash", "pool_address", "token_in_address", "token_out_address", "amount_in", "amount_out"}
+	transferLogColumns = []string{"block_number", "block_hash", "token_address", "from_address", "to_address", "amount"}
+)
+
+func tradeLogsInsert(table string, logs []common.TradeLog) sq.InsertBuilder {
+	insert := sq.Insert(table).Columns(tradeLogColumns...)
+	for _, l := range logs {
+		insert = insert.Values(l.BlockNumber, l.BlockHash, l.PoolAddress, l.TokenInAddress, l.TokenOutAddress, l.AmountIn, l.AmountOut)
+	}
+	return insert
+}
+
+func transferLogsInsert(table string, logs []common.TransferLog) sq.InsertBuilder {
+	insert := sq.Insert(table).Columns(transferLogColumns...)
+	for _, l := range logs {
+		insert = insert.Values(l.BlockNumber, l.BlockHash, l.TokenAddress, l.FromAddress, l.ToAddress, l.Amount)
+	}
+	return insert
+}
+
+func (pg *Postgres) InsertTradeLogs(ctx context.Context, table string, logs []common.TradeLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	query, args, err := tradeLogsInsert(table, logs).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "InsertTradeLogs", query, args...)
+	return err
+}
+
+func (pg *Postgres) InsertTransferLogs(ctx context.Context, table string, logs []common.TransferLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	query, args, err := transferLogsInsert(table, logs).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "InsertTransferLogs", query, args...)
+	return err
+}
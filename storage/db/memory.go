@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// Memory keeps the logs and the worker state in memory, for local runs and tests without a database.
+type Memory struct {
+	mu        sync.RWMutex
+	trades    map[string][]common.TradeLog
+	transfers map[string][]common.TransferLog
+	states    map[string][]byte
+	// seen tokens by chain and address
+	seen map[string]map[string]common.SeenToken
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		trades:    make(map[string][]common.TradeLog),
+		transfers: make(map[string][]common.TransferLog),
+		states:    make(map[string][]byte),
+		seen:      make(map[string]map[string]common.SeenToken),
+	}
+}
+
+func blockInRange(block, from, to int64) bool {
+	return block >= from && block <= to
+}
+
+func (m *Memory) InsertTradeLogs(_ context.Context, table string, logs []common.TradeLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trades[table] = append(m.trades[table], logs...)
+	return nil
+}
+
+func (m *Memory) InsertTransferLogs(_ context.Context, table string, logs []common.TransferLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transfers[table] = append(m.transfers[table], logs...)
+	return nil
+}
+
+func (m *Memory) GetLastStoredBlock(_ context.Context, table string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result int64
+	for _, l := range m.trades[table] {
+		if l.BlockNumber > result {
+			result = l.BlockNumber
+		}
+	}
+	for _, l := range m.transfers[table] {
+		if l.BlockNumber > result {
+			result = l.BlockNumber
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) GetUniqueTokenAddressByRangeForTrade(_ context.Context, table string, from, to int64) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	seen := map[string]bool{}
+	result := []string{}
+	for _, l := range m.trades[table] {
+		if !blockInRange(l.BlockNumber, from, to) {
+			continue
+		}
+		for _, a := range []string{l.TokenInAddress, l.TokenOutAddress} {
+			if !seen[a] {
+				seen[a] = true
+				result = append(result, a)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) GetUniqueTokenAddressByRangeForTransfer(_ context.Context, table string, from, to int64) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	seen := map[string]bool{}
+	result := []string{}
+	for _, l := range m.transfers[table] {
+		if blockInRange(l.BlockNumber, from, to) && !seen[l.TokenAddress] {
+			seen[l.TokenAddress] = true
+			result = append(result, l.TokenAddress)
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) GetTradeCountByRange(_ context.Context, table string, from, to int64) (map[string]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := map[string]int64{}
+	for _, l := range m.trades[table] {
+		if blockInRange(l.BlockNumber, from, to) {
+			result[l.TokenInAddress]++
+			result[l.TokenOutAddress]++
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) UpdateSeenTokens(_ context.Context, chainID, tradeTable, transferTable string, from, to int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ranges := map[string]common.SeenToken{}
+	observe := func(address string, block int64) {
+		address = strings.ToLower(address)
+		t, exist := ranges[address]
+		if !exist {
+			ranges[address] = common.SeenToken{ChainID: chainID, Address: address, FirstSeenBlock: block, LastSeenBlock: block}
+			return
+		}
+		if block < t.FirstSeenBlock {
+			t.FirstSeenBlock = block
+		}
+		if block > t.LastSeenBlock {
+			t.LastSeenBlock = block
+		}
+		ranges[address] = t
+	}
+	for _, l := range m.trades[tradeTable] {
+		if blockInRange(l.BlockNumber, from, to) {
+			observe(l.TokenInAddress, l.BlockNumber)
+			observe(l.TokenOutAddress, l.BlockNumber)
+		}
+	}
+	for _, l := range m.transfers[transferTable] {
+		if blockInRange(l.BlockNumber, from, to) {
+			observe(l.TokenAddress, l.BlockNumber)
+		}
+	}
+
+	seen := m.seen[chainID]
+	if seen == nil {
+		seen = make(map[string]common.SeenToken)
+		m.seen[chainID] = seen
+	}
+	for a, t := range ranges {
+		if current, exist := seen[a]; exist {
+			if current.FirstSeenBlock < t.FirstSeenBlock {
+				t.FirstSeenBlock = current.FirstSeenBlock
+			}
+			if current.LastSeenBlock > t.LastSeenBlock {
+				t.LastSeenBlock = current.LastSeenBlock
+			}
+		}
+		seen[a] = t
+	}
+	return int64(len(ranges)), nil
+}
+
+func (m *Memory) GetSeenTokensByRange(_ context.Context, chainID string, from, to int64, after common.SeenTokenCursor,
+	limit uint64) ([]common.SeenToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := []common.SeenToken{}
+	for _, t := range m.seen[chainID] {
+		if !blockInRange(t.LastSeenBlock, from, to) {
+			continue
+		}
+		if after != (common.SeenTokenCursor{}) && (t.LastSeenBlock < after.LastSeenBlock ||
+			t.LastSeenBlock == after.LastSeenBlock && t.Address <= after.Address) {
+			continue
+		}
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].LastSeenBlock != result[j].LastSeenBlock {
+			return result[i].LastSeenBlock < result[j].LastSeenBlock
+		}
+		return result[i].Address < result[j].Address
+	})
+	if uint64(len(result)) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *Memory) GetWorkerState(_ context.Context, name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state, exist := m.states[name]
+	if !exist {
+		return nil, nil
+	}
+	return append([]byte(nil), state...), nil
+}
+
+func (m *Memory) SaveWorkerState(_ context.Context, name string, state []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[name] = append([]byte(nil), state...)
+	return nil
+}
+
+func (m *Memory) DeleteWorkerState(_ context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.states, name)
+	return nil
+}
+
+func (m *Memory) DeleteWorkerStates(_ context.Context, name string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := int64(0)
+	for n := range m.states {
+		if n == name || strings.HasPrefix(n, name+":") {
+			delete(m.states, n)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
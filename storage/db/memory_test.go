@@ -0,0 +1,18 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"github.com/kv-base-hack/base-token-rate/storage/db/dbtest"
+)
+
+func TestMemory(t *testing.T) {
+	err := dbtest.TestDB(context.Background(), func() (dbtest.Backend, error) {
+		return db.NewMemory(), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
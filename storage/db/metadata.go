@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// MetadataStore is the registry of token contract metadata.
+type MetadataStore interface {
+	GetTokenMetadata(ctx context.Context, chainID string, addresses []string) (map[string]common.TokenMetadata, error)
+	SaveTokenMetadata(ctx context.Context, metadata []common.TokenMetadata) error
+	// GetTokenFirstSeen returns the first transfer of the tokens found in the transfer logs and the
+	// receiver of their first mint.
+	GetTokenFirstSeen(ctx context.Context, table string, addresses []string) (map[string]common.TokenFirstSeen, error)
+}
+
+var tokenMetadataColumns = []string{"chain_id", "address", "name", "symbol", "decimals", "total_supply", "first_seen_block", "first_minter"}
+
+func (pg *Postgres) GetTokenMetadata(ctx context.Context, chainID string, addresses []string) (map[string]common.TokenMetadata, error) {
+	result := make(map[string]common.TokenMetadata, len(addresses))
+	if len(addresses) == 0 {
+		return result, nil
+	}
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select(tokenMetadataColumns...).From(TokenMetadata).
+		Where(sq.Eq{"chain_id": chainID, "address": lowerAll(addresses)}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []common.TokenMetadata
+	if err := pg.selectRows(ctx, "GetTokenMetadata", &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, m := range rows {
+		result[m.Address] = m
+	}
+	return result, nil
+}
+
+func (pg *Postgres) SaveTokenMetadata(ctx context.Context, metadata []common.TokenMetadata) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+	insert := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert(TokenMetadata).Columns(append(tokenMetadataColumns, "updated_at")...)
+	for _, m := range metadata {
+		insert = insert.Values(m.ChainID, strings.ToLower(m.Address), m.Name, m.Symbol, m.Decimals, m.TotalSupply,
+			m.FirstSeenBlock, m.FirstMinter, sq.Expr("NOW()"))
+	}
+	query, args, err := insert.Suffix("ON CONFLICT (chain_id, address) DO UPDATE SET " +
+		"name = EXCLUDED.name, symbol = EXCLUDED.symbol, decimals = EXCLUDED.decimals, " +
+		"total_supply = EXCLUDED.total_supply, first_seen_block = EXCLUDED.first_seen_block, " +
+		"first_minter = EXCLUDED.first_minter, updated_at = EXCLUDED.updated_at").ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "SaveTokenMetadata", query, args...)
+	return err
+}
+
+func (pg *Postgres) GetTokenFirstSeen(ctx context.Context, table string, addresses []string) (map[string]common.TokenFirstSeen, error) {
+	result := make(map[string]common.TokenFirstSeen, len(addresses))
+	if len(addresses) == 0 {
+		return result, nil
+	}
+	tokens := sq.Eq{"LOWER(token_address)": lowerAll(addresses)}
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("LOWER(token_address) AS token_address", "MIN(block_number) AS first_seen_block").
+		From(table).Where(tokens).
+		GroupBy("LOWER(token_address)").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []common.TokenFirstSeen
+	if err := pg.selectRows(ctx, "GetTokenFirstSeen", &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.TokenAddress] = r
+	}
+
+	query, args, err = sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("DISTINCT ON (LOWER(token_address)) LOWER(token_address) AS token_address", "LOWER(to_address) AS first_minter").
+		From(table).Where(sq.And{tokens, sq.Eq{"LOWER(from_address)": zeroAddress}}).
+		OrderBy("LOWER(token_address)", "block_number").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var minters []common.TokenFirstSeen
+	if err := pg.selectRows(ctx, "GetTokenFirstMinter", &minters, query, args...); err != nil {
+		return nil, err
+	}
+	for _, m := range minters {
+		r := result[m.TokenAddress]
+		r.FirstMinter = m.FirstMinter
+		result[m.TokenAddress] = r
+	}
+	return result, nil
+}
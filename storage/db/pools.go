@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// PoolRegistry stores the pools seen in the trade logs and the history of their reserves.
+type PoolRegistry interface {
+	// GetPoolAddressesByRange returns the pools traded in the block range, from the pool_address of the trade logs.
+	GetPoolAddressesByRange(ctx context.Context, table string, from, to int64) ([]string, error)
+	GetPools(ctx context.Context, chainID string, addresses []string) (map[string]common.LiquidityPool, error)
+	// GetPoolsOfTokens returns the pools pairing one of the tokens with one of the anchors.
+	GetPoolsOfTokens(ctx context.Context, chainID string, tokens, anchors []string) ([]common.LiquidityPool, error)
+	SavePools(ctx context.Context, pools []common.LiquidityPool) error
+	InsertPoolReserves(ctx context.Context, reserves []common.PoolReserve) error
+	GetPoolReserves(ctx context.Context, poolAddress string, from, to time.Time) ([]common.PoolReserve, error)
+}
+
+var poolColumns = []string{"chain_id", "address", "kind", "factory", "token0", "token1", "fee", "first_seen_block"}
+var poolReserveColumns = []string{"pool_address", "block_number", "reserve0", "reserve1", "sqrt_price_x96", "price0", "observed_at"}
+
+func (pg *Postgres) GetPoolAddressesByRange(ctx context.Context, table string, from, to int64) ([]string, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("DISTINCT LOWER(pool_address)").From(table).
+		Where(sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	err = pg.selectRows(ctx, "GetPoolAddressesByRange", &result, query, args...)
+	return result, err
+}
+
+func (pg *Postgres) GetPools(ctx context.Context, chainID string, addresses []string) (map[string]common.LiquidityPool, error) {
+	result := make(map[string]common.LiquidityPool, len(addresses))
+	if len(addresses) == 0 {
+		return result, nil
+	}
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select(poolColumns...).From(Pools).
+		Where(sq.Eq{"chain_id": chainID, "address": lowerAll(addresses)}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []common.LiquidityPool
+	if err := pg.selectRows(ctx, "GetPools", &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, p := range rows {
+		result[p.Address] = p
+	}
+	return result, nil
+}
+
+func (pg *Postgres) GetPoolsOfTokens(ctx context.Context, chainID string, tokens, anchors []string) ([]common.LiquidityPool, error) {
+	if len(tokens) == 0 || len(anchors) == 0 {
+		return nil, nil
+	}
+	lowerTokens, lowerAnchors := lowerAll(tokens), lowerAll(anchors)
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select(poolColumns...).From(Pools).
+		Where(sq.And{
+			sq.Eq{"chain_id": chainID},
+			sq.Or{
+				sq.Eq{"token0": lowerTokens, "token1": lowerAnchors},
+				sq.Eq{"token0": lowerAnchors, "token1": lowerTokens},
+			},
+		}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []common.LiquidityPool
+	err = pg.selectRows(ctx, "GetPoolsOfTokens", &result, query, args...)
+	return result, err
+}
+
+func (pg *Postgres) SavePools(ctx context.Context, pools []common.LiquidityPool) error {
+	if len(pools) == 0 {
+		return nil
+	}
+	insert := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert(Pools).Columns(poolColumns...)
+	for _, p := range pools {
+		insert = insert.Values(p.ChainID, p.Address, p.Kind, p.Factory, p.Token0, p.Token1, p.Fee, p.FirstSeenBlock)
+	}
+	query, args, err := insert.Suffix("ON CONFLICT (chain_id, address) DO NOTHING").ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "SavePools", query, args...)
+	return err
+}
+
+func (pg *Postgres) InsertPoolReserves(ctx context.Context, reserves []common.PoolReserve) error {
+	if len(reserves) == 0 {
+		return nil
+	}
+	insert := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert(PoolReserves).Columns(poolReserveColumns...)
+	for _, r := range reserves {
+		insert = insert.Values(r.PoolAddress, r.BlockNumber, r.Reserve0, r.Reserve1, r.SqrtPriceX96, r.Price0, r.ObservedAt)
+	}
+	query, args, err := insert.ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "InsertPoolReserves", query, args...)
+	return err
+}
+
+func (pg *Postgres) GetPoolReserves(ctx context.Context, poolAddress string, from, to time.Time) ([]common.PoolReserve, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select(poolReserveColumns...).From(PoolReserves).
+		Where(sq.And{
+			sq.Eq{"pool_address": strings.ToLower(poolAddress)},
+			sq.GtOrEq{"observed_at": from},
+			sq.LtOrEq{"observed_at": to},
+		}).
+		OrderBy("observed_at").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []common.PoolReserve
+	err = pg.selectRows(ctx, "GetPoolReserves", &result, query, args...)
+	return result, err
+}
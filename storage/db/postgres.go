@@ -1,15 +1,31 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/kv-base-hack/base-token-rate/lib/tracing"
 	_ "github.com/lib/pq" // sql driver name: "postgres"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 const (
 	BaseTradeLogs    = "base_trade_logs"
 	BaseTransferLogs = "base_transfer_logs"
+	RateWorkerState  = "rate_worker_state"
+	PriceAuditLog    = "price_audit_log"
+	TokenMetadata    = "token_metadata"
+	TokenDiscoveries = "token_discoveries"
+	Pools            = "pools"
+	PoolReserves     = "pool_reserves"
+	TokenCandles     = "token_candles"
+	SeenTokens       = "seen_tokens"
 )
 
 type Postgres struct {
@@ -24,22 +40,60 @@ func NewPostgres(db *sqlx.DB) *Postgres {
 	}
 }
 
-func (pg *Postgres) GetLastStoredBlock(table string) (int64, error) {
+func startQuerySpan(ctx context.Context, name string, query string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "db."+name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBStatement(query)))
+}
+
+func (pg *Postgres) get(ctx context.Context, name string, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := startQuerySpan(ctx, name, query)
+	err := pg.db.GetContext(ctx, dest, query, args...)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (pg *Postgres) selectRows(ctx context.Context, name string, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := startQuerySpan(ctx, name, query)
+	err := pg.db.SelectContext(ctx, dest, query, args...)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (pg *Postgres) exec(ctx context.Context, name string, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, name, query)
+	result, err := pg.db.ExecContext(ctx, query, args...)
+	tracing.EndSpan(span, err)
+	return result, err
+}
+
+func lowerAll(addresses []string) []string {
+	result := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		result = append(result, strings.ToLower(a))
+	}
+	return result
+}
+
+func joinColumns(columns []string) string {
+	return strings.Join(columns, ", ")
+}
+
+func (pg *Postgres) GetLastStoredBlock(ctx context.Context, table string) (int64, error) {
 	query, _, err := sq.
-		Select("MAX(block_number) as block_number").
+		Select("COALESCE(MAX(block_number), 0) as block_number").
 		From(table).ToSql()
 	if err != nil {
 		return 0, err
 	}
 	var result int64
-	err = pg.db.Get(&result, query)
+	err = pg.get(ctx, "GetLastStoredBlock", &result, query)
 	if err != nil {
 		return 0, err
 	}
 	return result, nil
 }
 
-func (pg *Postgres) GetUniqueTokenAddressByRangeForTrade(table string, from, to int64) ([]string, error) {
+func (pg *Postgres) GetUniqueTokenAddressByRangeForTrade(ctx context.Context, table string, from, to int64) ([]string, error) {
 	firstSelect := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
 		Select("token_in_address").From(table).
 		Where(sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}})
@@ -54,19 +108,101 @@ func (pg *Postgres) GetUniqueTokenAddressByRangeForTrade(table string, from, to
 		return nil, err
 	}
 	var result []string
-	err = pg.db.Select(&result, q, p...)
+	err = pg.selectRows(ctx, "GetUniqueTokenAddressByRangeForTrade", &result, q, p...)
 
 	return result, err
 }
 
-func (pg *Postgres) GetUniqueTokenAddressByRangeForTransfer(table string, from, to int64) ([]string, error) {
+func (pg *Postgres) GetUniqueTokenAddressByRangeForTransfer(ctx context.Context, table string, from, to int64) ([]string, error) {
 	query := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
 		Select("distinct(token_address)").From(table).
 		Where(sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}})
 
 	sql, args, _ := query.ToSql()
 	var result []string
-	err := pg.db.Select(&result, sql, args...)
+	err := pg.selectRows(ctx, "GetUniqueTokenAddressByRangeForTransfer", &result, sql, args...)
 
 	return result, err
 }
+
+type tokenTradeCount struct {
+	TokenAddress string `db:"token_address"`
+	Trades       int64  `db:"trades"`
+}
+
+func (pg *Postgres) GetTradeCountByRange(ctx context.Context, table string, from, to int64) (map[string]int64, error) {
+	inRange := sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}}
+	sql, args, _ := sq.Select("token_out_address AS token_address").From(table).Where(inRange).ToSql()
+	trades := sq.Select("token_in_address AS token_address").From(table).Where(inRange).
+		Suffix("UNION ALL "+sql, args...)
+
+	q, p, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("token_address", "COUNT(*) AS trades").
+		FromSelect(trades, "trades").
+		GroupBy("token_address").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []tokenTradeCount
+	if err := pg.selectRows(ctx, "GetTradeCountByRange", &rows, q, p...); err != nil {
+		return nil, err
+	}
+	result := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		result[r.TokenAddress] = r.Trades
+	}
+	return result, nil
+}
+
+func (pg *Postgres) GetWorkerState(ctx context.Context, name string) ([]byte, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("state").From(RateWorkerState).
+		Where(sq.Eq{"name": name}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var state []byte
+	err = pg.get(ctx, "GetWorkerState", &state, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return state, err
+}
+
+func (pg *Postgres) SaveWorkerState(ctx context.Context, name string, state []byte) error {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert(RateWorkerState).Columns("name", "state", "updated_at").
+		Values(name, string(state), sq.Expr("NOW()")).
+		Suffix("ON CONFLICT (name) DO UPDATE SET state = EXCLUDED.state, updated_at = EXCLUDED.updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "SaveWorkerState", query, args...)
+	return err
+}
+
+func (pg *Postgres) DeleteWorkerState(ctx context.Context, name string) error {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Delete(RateWorkerState).Where(sq.Eq{"name": name}).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "DeleteWorkerState", query, args...)
+	return err
+}
+
+func (pg *Postgres) DeleteWorkerStates(ctx context.Context, name string) (int64, error) {
+	prefix := name + ":"
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Delete(RateWorkerState).
+		Where(sq.Or{sq.Eq{"name": name}, sq.Expr("SUBSTR(name, 1, ?) = ?", len(prefix), prefix)}).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	result, err := pg.exec(ctx, "DeleteWorkerStates", query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,38 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"github.com/kv-base-hack/base-token-rate/storage/db/dbtest"
+)
+
+// the suite needs a migrated scratch database, its logs, seen tokens and worker state are deleted
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresDSNEnv)
+	}
+	database, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+	err = dbtest.TestDB(ctx, func() (dbtest.Backend, error) {
+		for _, table := range []string{db.BaseTradeLogs, db.BaseTransferLogs, db.SeenTokens, db.RateWorkerState} {
+			if _, err := database.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				return nil, err
+			}
+		}
+		return db.NewPostgres(database), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ReorgStore reads the block hashes of the logs and rewinds the data derived from reorged blocks.
+type ReorgStore interface {
+	CandleStore
+	// GetBlockHashes returns the hash of every block of the range with logs in the table.
+	GetBlockHashes(ctx context.Context, table string, from, to int64) (map[int64]string, error)
+	// RewindSeenTokens forgets the blocks from the reorged block in the seen tokens,
+	// it returns the tokens which were only seen in those blocks.
+	RewindSeenTokens(ctx context.Context, chainID string, from int64) ([]string, error)
+	DeleteTokenDiscoveriesFrom(ctx context.Context, chainID string, from int64) (int64, error)
+	// DeletePoolsFrom deletes the pools first seen and the reserves observed from the block.
+	DeletePoolsFrom(ctx context.Context, chainID string, from int64) error
+	// RewindTokenMetadata deletes the metadata of the tokens first seen from the block, it returns
+	// the tokens to register again from the rewritten logs.
+	RewindTokenMetadata(ctx context.Context, chainID string, from int64) ([]string, error)
+	// RewindCandles deletes the candles holding blocks from the reorged block, it returns the last
+	// block of the deleted candles by interval.
+	RewindCandles(ctx context.Context, chainID string, from int64) (map[int64]int64, error)
+}
+
+type blockHash struct {
+	Block int64  `db:"block_number"`
+	Hash  string `db:"block_hash"`
+}
+
+func (pg *Postgres) GetBlockHashes(ctx context.Context, table string, from, to int64) (map[int64]string, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("DISTINCT block_number", "block_hash").From(table).
+		Where(sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []blockHash
+	if err := pg.selectRows(ctx, "GetBlockHashes", &rows, query, args...); err != nil {
+		return nil, err
+	}
+	result := make(map[int64]string, len(rows))
+	for _, r := range rows {
+		result[r.Block] = r.Hash
+	}
+	return result, nil
+}
+
+func (pg *Postgres) RewindSeenTokens(ctx context.Context, chainID string, from int64) ([]string, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Delete(SeenTokens).
+		Where(sq.And{sq.Eq{"chain_id": chainID}, sq.GtOrEq{"first_seen_block": from}}).
+		Suffix("RETURNING address").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	if err := pg.selectRows(ctx, "RewindSeenTokens", &removed, query, args...); err != nil {
+		return nil, err
+	}
+	// the tokens seen before keep an upper bound of their last block, the rescan of the range moves it again
+	query, args, err = sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Update(SeenTokens).Set("last_seen_block", from-1).
+		Where(sq.And{sq.Eq{"chain_id": chainID}, sq.GtOrEq{"last_seen_block": from}}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pg.exec(ctx, "RewindSeenTokens", query, args...); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+func (pg *Postgres) DeleteTokenDiscoveriesFrom(ctx context.Context, chainID string, from int64) (int64, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Delete(TokenDiscoveries).
+		Where(sq.And{sq.Eq{"chain_id": chainID}, sq.GtOrEq{"first_seen_block": from}}).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	result, err := pg.exec(ctx, "DeleteTokenDiscoveriesFrom", query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (pg *Postgres) DeletePoolsFrom(ctx context.Context, chainID string, from int64) error {
+	pools, poolArgs, _ := sq.Select("address").From(Pools).Where(sq.Eq{"chain_id": chainID}).ToSql()
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Delete(PoolReserves).
+		Where(sq.And{sq.GtOrEq{"block_number": from}, sq.Expr("pool_address IN ("+pools+")", poolArgs...)}).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := pg.exec(ctx, "DeletePoolsFrom", query, args...); err != nil {
+		return err
+	}
+	query, args, err = sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Delete(Pools).
+		Where(sq.And{sq.Eq{"chain_id": chainID}, sq.GtOrEq{"first_seen_block": from}}).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = pg.exec(ctx, "DeletePoolsFrom", query, args...)
+	return err
+}
+
+func (pg *Postgres) RewindTokenMetadata(ctx context.Context, chainID string, from int64) ([]string, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Delete(TokenMetadata).
+		Where(sq.And{sq.Eq{"chain_id": chainID}, sq.GtOrEq{"first_seen_block": from}}).
+		Suffix("RETURNING address").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	err = pg.selectRows(ctx, "RewindTokenMetadata", &removed, query, args...)
+	return removed, err
+}
+
+type rewoundCandle struct {
+	IntervalBlocks int64 `db:"interval_blocks"`
+	StartBlock     int64 `db:"start_block"`
+}
+
+func (pg *Postgres) RewindCandles(ctx context.Context, chainID string, from int64) (map[int64]int64, error) {
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Delete(TokenCandles).
+		Where(sq.And{sq.Eq{"chain_id": chainID}, sq.Expr("start_block + interval_blocks > ?", from)}).
+		Suffix("RETURNING interval_blocks, start_block").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []rewoundCandle
+	if err := pg.selectRows(ctx, "RewindCandles", &rows, query, args...); err != nil {
+		return nil, err
+	}
+	result := map[int64]int64{}
+	for _, c := range rows {
+		if last := c.StartBlock + c.IntervalBlocks - 1; last > result[c.IntervalBlocks] {
+			result[c.IntervalBlocks] = last
+		}
+	}
+	return result, nil
+}
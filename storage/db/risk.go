@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/lib/pq"
+)
+
+// RiskStore gets the trade and transfer patterns the token risk is computed from.
+type RiskStore interface {
+	// GetTradeSides counts the buys, trades receiving the token, and the sells since fromBlock.
+	GetTradeSides(ctx context.Context, table string, tokens []string, fromBlock int64) (map[string]common.TradeSides, error)
+	// GetHolderConcentration returns the holders and the share of the 10 largest of them, the excluded
+	// addresses such as pools and burn addresses aren't holders.
+	GetHolderConcentration(ctx context.Context, table string, tokens, excluded []string) (map[string]common.HolderConcentration, error)
+	// GetTransferSenders counts the transfers since fromBlock and their distinct senders, except the excluded addresses.
+	GetTransferSenders(ctx context.Context, table string, tokens []string, fromBlock int64, excluded []string) (map[string]common.TransferSenders, error)
+}
+
+func (pg *Postgres) GetTradeSides(ctx context.Context, table string, tokens []string, fromBlock int64) (map[string]common.TradeSides, error) {
+	result := make(map[string]common.TradeSides, len(tokens))
+	if len(tokens) == 0 {
+		return result, nil
+	}
+	lower := lowerAll(tokens)
+	sql, args, _ := sq.Select("LOWER(token_in_address) AS token_address", "0 AS buy", "1 AS sell").From(table).
+		Where(sq.And{sq.Eq{"LOWER(token_in_address)": lower}, sq.GtOrEq{"block_number": fromBlock}}).ToSql()
+	trades := sq.Select("LOWER(token_out_address) AS token_address", "1 AS buy", "0 AS sell").From(table).
+		Where(sq.And{sq.Eq{"LOWER(token_out_address)": lower}, sq.GtOrEq{"block_number": fromBlock}}).
+		Suffix("UNION ALL "+sql, args...)
+	q, p, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("token_address", "SUM(buy) AS buys", "SUM(sell) AS sells").
+		FromSelect(trades, "trades").
+		GroupBy("token_address").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []common.TradeSides
+	if err := pg.selectRows(ctx, "GetTradeSides", &rows, q, p...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.TokenAddress] = r
+	}
+	return result, nil
+}
+
+func (pg *Postgres) GetHolderConcentration(ctx context.Context, table string, tokens, excluded []string) (map[string]common.HolderConcentration, error) {
+	result := make(map[string]common.HolderConcentration, len(tokens))
+	if len(tokens) == 0 {
+		return result, nil
+	}
+	lower := lowerAll(tokens)
+	sql, args, _ := sq.Select("LOWER(token_address) AS token_address", "LOWER(from_address) AS holder", "-amount AS amount").
+		From(table).Where(sq.Eq{"LOWER(token_address)": lower}).ToSql()
+	flows := sq.Select("LOWER(token_address) AS token_address", "LOWER(to_address) AS holder", "amount").
+		From(table).Where(sq.Eq{"LOWER(token_address)": lower}).
+		Suffix("UNION ALL "+sql, args...)
+	balances := sq.Select("token_address", "SUM(amount) AS balance").
+		FromSelect(flows, "flows").
+		Where(sq.Expr("NOT (holder = ANY(?))", pq.Array(lowerAll(excluded)))).
+		GroupBy("token_address", "holder").
+		Having("SUM(amount) > 0")
+	ranked := sq.Select("token_address", "balance",
+		"ROW_NUMBER() OVER (PARTITION BY token_address ORDER BY balance DESC) AS rank",
+		"SUM(balance) OVER (PARTITION BY token_address) AS total").
+		FromSelect(balances, "balances")
+	q, p, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("token_address", "COUNT(*) AS holders",
+			"COALESCE(SUM(balance) FILTER (WHERE rank <= 10) / NULLIF(MAX(total), 0), 0) AS top10_share").
+		FromSelect(ranked, "ranked").
+		GroupBy("token_address").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []common.HolderConcentration
+	if err := pg.selectRows(ctx, "GetHolderConcentration", &rows, q, p...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.TokenAddress] = r
+	}
+	return result, nil
+}
+
+func (pg *Postgres) GetTransferSenders(ctx context.Context, table string, tokens []string, fromBlock int64,
+	excluded []string) (map[string]common.TransferSenders, error) {
+	result := make(map[string]common.TransferSenders, len(tokens))
+	if len(tokens) == 0 {
+		return result, nil
+	}
+	q, p, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("LOWER(token_address) AS token_address", "COUNT(*) AS transfers", "COUNT(DISTINCT LOWER(from_address)) AS senders").
+		From(table).
+		Where(sq.And{
+			sq.Eq{"LOWER(token_address)": lowerAll(tokens)},
+			sq.GtOrEq{"block_number": fromBlock},
+			sq.Expr("NOT (LOWER(from_address) = ANY(?))", pq.Array(lowerAll(excluded))),
+		}).
+		GroupBy("LOWER(token_address)").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []common.TransferSenders
+	if err := pg.selectRows(ctx, "GetTransferSenders", &rows, q, p...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.TokenAddress] = r
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+var seenTokenColumns = []string{"chain_id", "address", "first_seen_block", "last_seen_block"}
+
+func (pg *Postgres) UpdateSeenTokens(ctx context.Context, chainID, tradeTable, transferTable string, from, to int64) (int64, error) {
+	inRange := sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}}
+	transferSql, transferArgs, _ := sq.Select("LOWER(token_address) AS address", "block_number").From(transferTable).
+		Where(inRange).ToSql()
+	tokenOutSql, tokenOutArgs, _ := sq.Select("LOWER(token_out_address) AS address", "block_number").From(tradeTable).
+		Where(inRange).ToSql()
+	seen := sq.Select("LOWER(token_in_address) AS address", "block_number").From(tradeTable).
+		Where(inRange).
+		Suffix("UNION ALL "+tokenOutSql, tokenOutArgs...).
+		Suffix("UNION ALL "+transferSql, transferArgs...)
+	grouped := sq.Select().Column(sq.Expr("?::TEXT", chainID)).
+		Columns("address", "MIN(block_number)", "MAX(block_number)").
+		FromSelect(seen, "seen").
+		GroupBy("address")
+
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert(SeenTokens).Columns(seenTokenColumns...).
+		Select(grouped).
+		Suffix("ON CONFLICT (chain_id, address) DO UPDATE SET " +
+			"first_seen_block = LEAST(seen_tokens.first_seen_block, EXCLUDED.first_seen_block), " +
+			"last_seen_block = GREATEST(seen_tokens.last_seen_block, EXCLUDED.last_seen_block)").ToSql()
+	if err != nil {
+		return 0, err
+	}
+	result, err := pg.exec(ctx, "UpdateSeenTokens", query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (pg *Postgres) GetSeenTokensByRange(ctx context.Context, chainID string, from, to int64, after common.SeenTokenCursor,
+	limit uint64) ([]common.SeenToken, error) {
+	where := sq.And{
+		sq.Eq{"chain_id": chainID},
+		sq.GtOrEq{"last_seen_block": from},
+		sq.LtOrEq{"last_seen_block": to},
+	}
+	if after != (common.SeenTokenCursor{}) {
+		where = append(where, sq.Expr("(last_seen_block, address) > (?, ?)", after.LastSeenBlock, after.Address))
+	}
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select(seenTokenColumns...).From(SeenTokens).
+		Where(where).
+		OrderBy("last_seen_block", "address").
+		Limit(limit).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []common.SeenToken
+	err = pg.selectRows(ctx, "GetSeenTokensByRange", &result, query, args...)
+	return result, err
+}
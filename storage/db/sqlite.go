@@ -0,0 +1,293 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	_ "modernc.org/sqlite" // sql driver name: "sqlite"
+)
+
+// sqliteSchema creates the tables of the DB interface, the logs included so a local run can be fed.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS base_trade_logs
+(
+    block_number      INTEGER NOT NULL,
+    block_hash        TEXT    NOT NULL DEFAULT '',
+    pool_address      TEXT    NOT NULL DEFAULT '',
+    token_in_address  TEXT    NOT NULL,
+    token_out_address TEXT    NOT NULL,
+    amount_in         REAL    NOT NULL DEFAULT 0,
+    amount_out        REAL    NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS base_trade_logs_block_number_idx ON base_trade_logs (block_number);
+
+CREATE TABLE IF NOT EXISTS base_transfer_logs
+(
+    block_number  INTEGER NOT NULL,
+    block_hash    TEXT    NOT NULL DEFAULT '',
+    token_address TEXT    NOT NULL,
+    from_address  TEXT    NOT NULL DEFAULT '',
+    to_address    TEXT    NOT NULL DEFAULT '',
+    amount        REAL    NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS base_transfer_logs_block_number_idx ON base_transfer_logs (block_number);
+
+CREATE TABLE IF NOT EXISTS rate_worker_state
+(
+    name       TEXT PRIMARY KEY,
+    state      BLOB NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS seen_tokens
+(
+    chain_id         TEXT    NOT NULL,
+    address          TEXT    NOT NULL,
+    first_seen_block INTEGER NOT NULL,
+    last_seen_block  INTEGER NOT NULL,
+    PRIMARY KEY (chain_id, address)
+);
+CREATE INDEX IF NOT EXISTS seen_tokens_last_seen_idx ON seen_tokens (chain_id, last_seen_block, address);
+`
+
+// SQLite implements the DB interface on a sqlite file, the features which need postgres aren't available.
+type SQLite struct {
+	db *sqlx.DB
+}
+
+// NewSQLite opens the sqlite database at path and creates its tables, ":memory:" keeps it in memory.
+func NewSQLite(path string) (*SQLite, error) {
+	database, err := sqlx.Connect("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite allows one writer, and every connection to ":memory:" opens its own database
+	database.SetMaxOpenConns(1)
+	if _, err := database.Exec(sqliteSchema); err != nil {
+		_ = database.Close()
+		return nil, err
+	}
+	return &SQLite{db: database}, nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+func startSQLiteSpan(ctx context.Context, name string, query string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "db."+name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemSqlite, semconv.DBStatement(query)))
+}
+
+func (s *SQLite) get(ctx context.Context, name string, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := startSQLiteSpan(ctx, name, query)
+	err := s.db.GetContext(ctx, dest, query, args...)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (s *SQLite) selectRows(ctx context.Context, name string, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := startSQLiteSpan(ctx, name, query)
+	err := s.db.SelectContext(ctx, dest, query, args...)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (s *SQLite) exec(ctx context.Context, name string, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSQLiteSpan(ctx, name, query)
+	result, err := s.db.ExecContext(ctx, query, args...)
+	tracing.EndSpan(span, err)
+	return result, err
+}
+
+func (s *SQLite) InsertTradeLogs(ctx context.Context, table string, logs []common.TradeLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	query, args, err := tradeLogsInsert(table, logs).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(ctx, "InsertTradeLogs", query, args...)
+	return err
+}
+
+func (s *SQLite) InsertTransferLogs(ctx context.Context, table string, logs []common.TransferLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	query, args, err := transferLogsInsert(table, logs).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(ctx, "InsertTransferLogs", query, args...)
+	return err
+}
+
+func (s *SQLite) GetLastStoredBlock(ctx context.Context, table string) (int64, error) {
+	query, _, err := sq.
+		Select("COALESCE(MAX(block_number), 0) as block_number").
+		From(table).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	var result int64
+	err = s.get(ctx, "GetLastStoredBlock", &result, query)
+	return result, err
+}
+
+func (s *SQLite) GetUniqueTokenAddressByRangeForTrade(ctx context.Context, table string, from, to int64) ([]string, error) {
+	inRange := sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}}
+	sql, args, _ := sq.Select("token_out_address").From(table).Where(inRange).ToSql()
+	query, params, err := sq.Select("token_in_address").From(table).Where(inRange).
+		Suffix("UNION "+sql, args...).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	err = s.selectRows(ctx, "GetUniqueTokenAddressByRangeForTrade", &result, query, params...)
+	return result, err
+}
+
+func (s *SQLite) GetUniqueTokenAddressByRangeForTransfer(ctx context.Context, table string, from, to int64) ([]string, error) {
+	query, args, err := sq.Select("DISTINCT token_address").From(table).
+		Where(sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	err = s.selectRows(ctx, "GetUniqueTokenAddressByRangeForTransfer", &result, query, args...)
+	return result, err
+}
+
+func (s *SQLite) GetTradeCountByRange(ctx context.Context, table string, from, to int64) (map[string]int64, error) {
+	inRange := sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}}
+	sql, args, _ := sq.Select("token_out_address AS token_address").From(table).Where(inRange).ToSql()
+	trades := sq.Select("token_in_address AS token_address").From(table).Where(inRange).
+		Suffix("UNION ALL "+sql, args...)
+	query, params, err := sq.Select("token_address", "COUNT(*) AS trades").
+		FromSelect(trades, "trades").
+		GroupBy("token_address").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []tokenTradeCount
+	if err := s.selectRows(ctx, "GetTradeCountByRange", &rows, query, params...); err != nil {
+		return nil, err
+	}
+	result := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		result[r.TokenAddress] = r.Trades
+	}
+	return result, nil
+}
+
+func (s *SQLite) UpdateSeenTokens(ctx context.Context, chainID, tradeTable, transferTable string, from, to int64) (int64, error) {
+	inRange := sq.And{sq.GtOrEq{"block_number": from}, sq.LtOrEq{"block_number": to}}
+	transferSql, transferArgs, _ := sq.Select("LOWER(token_address) AS address", "block_number").From(transferTable).
+		Where(inRange).ToSql()
+	tokenOutSql, tokenOutArgs, _ := sq.Select("LOWER(token_out_address) AS address", "block_number").From(tradeTable).
+		Where(inRange).ToSql()
+	seen := sq.Select("LOWER(token_in_address) AS address", "block_number").From(tradeTable).
+		Where(inRange).
+		Suffix("UNION ALL "+tokenOutSql, tokenOutArgs...).
+		Suffix("UNION ALL "+transferSql, transferArgs...)
+	// the WHERE keeps sqlite from parsing ON CONFLICT as a join constraint
+	grouped := sq.Select().Column(sq.Expr("?", chainID)).
+		Columns("address", "MIN(block_number)", "MAX(block_number)").
+		FromSelect(seen, "seen").
+		Where("true").
+		GroupBy("address")
+
+	query, args, err := sq.Insert(SeenTokens).Columns(seenTokenColumns...).
+		Select(grouped).
+		Suffix("ON CONFLICT (chain_id, address) DO UPDATE SET " +
+			"first_seen_block = MIN(seen_tokens.first_seen_block, excluded.first_seen_block), " +
+			"last_seen_block = MAX(seen_tokens.last_seen_block, excluded.last_seen_block)").ToSql()
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.exec(ctx, "UpdateSeenTokens", query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *SQLite) GetSeenTokensByRange(ctx context.Context, chainID string, from, to int64, after common.SeenTokenCursor,
+	limit uint64) ([]common.SeenToken, error) {
+	where := sq.And{
+		sq.Eq{"chain_id": chainID},
+		sq.GtOrEq{"last_seen_block": from},
+		sq.LtOrEq{"last_seen_block": to},
+	}
+	if after != (common.SeenTokenCursor{}) {
+		where = append(where, sq.Expr("(last_seen_block, address) > (?, ?)", after.LastSeenBlock, after.Address))
+	}
+	query, args, err := sq.Select(seenTokenColumns...).From(SeenTokens).
+		Where(where).
+		OrderBy("last_seen_block", "address").
+		Limit(limit).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var result []common.SeenToken
+	err = s.selectRows(ctx, "GetSeenTokensByRange", &result, query, args...)
+	return result, err
+}
+
+func (s *SQLite) GetWorkerState(ctx context.Context, name string) ([]byte, error) {
+	query, args, err := sq.Select("state").From(RateWorkerState).
+		Where(sq.Eq{"name": name}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var state []byte
+	err = s.get(ctx, "GetWorkerState", &state, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return state, err
+}
+
+func (s *SQLite) SaveWorkerState(ctx context.Context, name string, state []byte) error {
+	query, args, err := sq.Insert(RateWorkerState).Columns("name", "state", "updated_at").
+		Values(name, state, sq.Expr("CURRENT_TIMESTAMP")).
+		Suffix("ON CONFLICT (name) DO UPDATE SET state = excluded.state, updated_at = excluded.updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(ctx, "SaveWorkerState", query, args...)
+	return err
+}
+
+func (s *SQLite) DeleteWorkerState(ctx context.Context, name string) error {
+	query, args, err := sq.Delete(RateWorkerState).Where(sq.Eq{"name": name}).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(ctx, "DeleteWorkerState", query, args...)
+	return err
+}
+
+func (s *SQLite) DeleteWorkerStates(ctx context.Context, name string) (int64, error) {
+	prefix := name + ":"
+	query, args, err := sq.Delete(RateWorkerState).
+		Where(sq.Or{sq.Eq{"name": name}, sq.Expr("SUBSTR(name, 1, ?) = ?", len(prefix), prefix)}).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.exec(ctx, "DeleteWorkerStates", query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
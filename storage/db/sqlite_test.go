@@ -0,0 +1,23 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"github.com/kv-base-hack/base-token-rate/storage/db/dbtest"
+)
+
+func TestSQLite(t *testing.T) {
+	dir := t.TempDir()
+	files := 0
+	err := dbtest.TestDB(context.Background(), func() (dbtest.Backend, error) {
+		files++
+		return db.NewSQLite(filepath.Join(dir, fmt.Sprintf("test-%d.db", files)))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/lib/pq"
+)
+
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// SupplyStore computes token supply from the transfer logs.
+type SupplyStore interface {
+	// GetTokenSupply sums the mints and the transfers to the burn addresses of the tokens, the locked
+	// supply is the balance of the locker addresses. The indexer writes the amounts of the transfer logs
+	// in token units, already divided by the decimals, the sums aren't scaled.
+	GetTokenSupply(ctx context.Context, table string, tokens, burnAddresses, lockerAddresses []string) (map[string]common.TokenSupply, error)
+}
+
+type tokenSupply struct {
+	TokenAddress string  `db:"token_address"`
+	Minted       float64 `db:"minted"`
+	Burned       float64 `db:"burned"`
+	Locked       float64 `db:"locked"`
+}
+
+func (pg *Postgres) GetTokenSupply(ctx context.Context, table string, tokens, burnAddresses, lockerAddresses []string) (map[string]common.TokenSupply, error) {
+	result := make(map[string]common.TokenSupply, len(tokens))
+	if len(tokens) == 0 {
+		return result, nil
+	}
+	burn := lowerAll(burnAddresses)
+	lockers := lowerAll(lockerAddresses)
+	query, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select("LOWER(token_address) AS token_address").
+		Column(sq.Expr("SUM(CASE WHEN LOWER(from_address) = ? THEN amount ELSE 0 END) AS minted", zeroAddress)).
+		Column(sq.Expr("SUM(CASE WHEN LOWER(to_address) = ANY(?) THEN amount ELSE 0 END) AS burned", pq.Array(burn))).
+		Column(sq.Expr("SUM(CASE WHEN LOWER(to_address) = ANY(?) THEN amount "+
+			"WHEN LOWER(from_address) = ANY(?) THEN -amount ELSE 0 END) AS locked", pq.Array(lockers), pq.Array(lockers))).
+		From(table).
+		Where(sq.Eq{"LOWER(token_address)": lowerAll(tokens)}).
+		GroupBy("LOWER(token_address)").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var rows []tokenSupply
+	if err := pg.selectRows(ctx, "GetTokenSupply", &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		total := r.Minted - r.Burned
+		circulating := total - r.Locked
+		if circulating < 0 {
+			circulating = 0
+		}
+		result[r.TokenAddress] = common.TokenSupply{
+			TotalSupply:       total,
+			CirculatingSupply: circulating,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package kv
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("kv")
+
+// Bolt stores the values in an embedded bbolt file, so a single binary keeps its snapshots over restarts.
+// A value is stored after its expiry time in unix nanoseconds, 0 if it doesn't expire.
+type Bolt struct {
+	db        *bolt.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	// the snapshots are published again every cycle, losing the last writes on a crash is fine
+	db.NoSync = true
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Bolt{db: db}, nil
+}
+
+func expired(entry []byte, now time.Time) bool {
+	expiresAt := int64(binary.BigEndian.Uint64(entry[:8]))
+	return expiresAt != 0 && now.UnixNano() > expiresAt
+}
+
+func (b *Bolt) Set(key string, value interface{}, expiration time.Duration) error {
+	data, err := toBytes(value)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	entry := make([]byte, 8+len(data))
+	if expiration > 0 {
+		binary.BigEndian.PutUint64(entry[:8], uint64(now.Add(expiration).UnixNano()))
+	}
+	copy(entry[8:], data)
+
+	b.mu.Lock()
+	sweep := now.Sub(b.lastSweep) >= sweepInterval
+	if sweep {
+		b.lastSweep = now
+	}
+	b.mu.Unlock()
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		if err := bucket.Put([]byte(key), entry); err != nil {
+			return err
+		}
+		if !sweep {
+			return nil
+		}
+		var keys [][]byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			if len(v) >= 8 && expired(v, now) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *Bolt) Get(_ context.Context, key string) ([]byte, error) {
+	var result []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		entry := tx.Bucket(boltBucket).Get([]byte(key))
+		if len(entry) < 8 || expired(entry, time.Now()) {
+			return ErrNotFound
+		}
+		// the entry is only valid during the transaction
+		result = append([]byte(nil), entry[8:]...)
+		return nil
+	})
+	return result, err
+}
+
+func (b *Bolt) Ping(context.Context) error {
+	return nil
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
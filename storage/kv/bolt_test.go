@@ -0,0 +1,39 @@
+package kv
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestBolt(t *testing.T) {
+	store, err := NewBolt(filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	testStore(t, store)
+}
+
+func TestBoltKeepsValuesOverRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+	store, err := NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("key", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	got, err := store.Get(context.Background(), "key")
+	if err != nil || string(got) != "value" {
+		t.Fatalf("got %q, %v after reopen", got, err)
+	}
+}
@@ -0,0 +1,33 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// ErrNotFound is returned by Get when the key doesn't exist or expired.
+var ErrNotFound = errors.New("key not found")
+
+// Store is where the workers publish the snapshots and the api reads them.
+type Store interface {
+	// Set stores a []byte or string value, a zero expiration keeps it forever.
+	Set(key string, value interface{}, expiration time.Duration) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+func toBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
@@ -0,0 +1,49 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testStore checks the behavior every store shares, the keys are prefixed so a shared redis isn't clobbered.
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	key := func(name string) string {
+		return "kv_test:" + name
+	}
+	if err := store.Ping(ctx); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	if _, err := store.Get(ctx, key("missing")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get missing key: got %v, want ErrNotFound", err)
+	}
+	if err := store.Set(key("bytes"), []byte(`{"a":1}`), 0); err != nil {
+		t.Fatalf("set bytes: %v", err)
+	}
+	if got, err := store.Get(ctx, key("bytes")); err != nil || string(got) != `{"a":1}` {
+		t.Fatalf("get bytes: got %q, %v", got, err)
+	}
+	if err := store.Set(key("bytes"), "overwritten", 0); err != nil {
+		t.Fatalf("set string: %v", err)
+	}
+	if got, err := store.Get(ctx, key("bytes")); err != nil || string(got) != "overwritten" {
+		t.Fatalf("get overwritten: got %q, %v", got, err)
+	}
+	if err := store.Set(key("int"), 1, 0); err == nil {
+		t.Fatal("set int: want an error")
+	}
+
+	if err := store.Set(key("expiring"), "value", 100*time.Millisecond); err != nil {
+		t.Fatalf("set expiring: %v", err)
+	}
+	if got, err := store.Get(ctx, key("expiring")); err != nil || string(got) != "value" {
+		t.Fatalf("get before expiry: got %q, %v", got, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, err := store.Get(ctx, key("expiring")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get after expiry: got %v, want ErrNotFound", err)
+	}
+}
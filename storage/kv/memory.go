@@ -0,0 +1,67 @@
+package kv
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Memory keeps the values in process, for single binary deployments and tests.
+type Memory struct {
+	mu        sync.RWMutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry)}
+}
+
+func (m *Memory) Set(key string, value interface{}, expiration time.Duration) error {
+	data, err := toBytes(value)
+	if err != nil {
+		return err
+	}
+	e := memoryEntry{value: append([]byte(nil), data...)}
+	if expiration > 0 {
+		e.expiresAt = time.Now().Add(expiration)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = e
+	// expired entries are swept on writes so keys which are never read again don't pile up
+	now := time.Now()
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return nil
+	}
+	m.lastSweep = now
+	for k, v := range m.entries {
+		if !v.expiresAt.IsZero() && now.After(v.expiresAt) {
+			delete(m.entries, k)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, exist := m.entries[key]
+	if !exist || !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), e.value...), nil
+}
+
+func (m *Memory) Ping(context.Context) error {
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
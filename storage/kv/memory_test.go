@@ -0,0 +1,23 @@
+package kv
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
+
+func TestMemoryCopiesValues(t *testing.T) {
+	store := NewMemory()
+	value := []byte("value")
+	if err := store.Set("key", value, 0); err != nil {
+		t.Fatal(err)
+	}
+	value[0] = 'V'
+	got, err := store.Get(context.Background(), "key")
+	if err != nil || string(got) != "value" {
+		t.Fatalf("got %q, %v, want the value at set time", got, err)
+	}
+}
@@ -0,0 +1,22 @@
+package kv
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisAddrEnv = "TEST_REDIS_ADDR"
+
+func TestRedis(t *testing.T) {
+	addr := os.Getenv(redisAddrEnv)
+	if addr == "" {
+		t.Skipf("%s not set", redisAddrEnv)
+	}
+	store := NewRedis(redis.NewClient(&redis.Options{Addr: addr}))
+	defer store.Close()
+	defer store.client.Del(context.Background(), "kv_test:bytes", "kv_test:expiring")
+	testStore(t, store)
+}
@@ -0,0 +1,93 @@
+package workers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+// SetAuditLog makes the worker append every published price change to the audit log.
+func (r *RateWorker) SetAuditLog(auditLog db.AuditLog) {
+	r.auditLog = auditLog
+}
+
+func rejectedQuote(p common.Pair, reason string) common.RejectedQuote {
+	return common.RejectedQuote{
+		DexID:        p.DexID,
+		Url:          p.Url,
+		PriceUsd:     p.PriceUsd,
+		LiquidityUsd: p.Liquidity.Usd,
+		VolumeH24:    p.Volume.H24,
+		Reason:       reason,
+	}
+}
+
+func auditKey(chainID, address string) string {
+	return chainID + ":" + strings.ToLower(address)
+}
+
+// recordAudit keeps the audit entry until the price it describes is published.
+func (r *RateWorker) recordAudit(a common.PriceAudit) {
+	if r.auditLog == nil {
+		return
+	}
+	r.audits[auditKey(a.ChainID, a.TokenAddress)] = a
+}
+
+func (r *RateWorker) recordCexAudits(at time.Time) {
+	for _, t := range r.cexTokens {
+		r.recordAudit(common.PriceAudit{
+			TokenAddress: t.Address,
+			ChainID:      t.ChainID,
+			Symbol:       t.Symbol,
+			UsdPrice:     t.UsdPrice,
+			SourcePrice:  common.SourcePriceCex,
+			PublishedAt:  at,
+		})
+	}
+}
+
+// writeAudits stores the published entries whose price changed since they were last audited.
+func (r *RateWorker) writeAudits(ctx context.Context, log *zap.SugaredLogger, published []common.Token) {
+	if r.auditLog == nil {
+		return
+	}
+	publishedKeys := make(map[string]bool, len(published))
+	for _, t := range published {
+		publishedKeys[auditKey(t.ChainID, t.Address)] = true
+	}
+	// a token coming back is audited again
+	for key := range r.lastAudited {
+		if !publishedKeys[key] {
+			delete(r.lastAudited, key)
+		}
+	}
+	if len(r.audits) == 0 {
+		return
+	}
+	changed := []common.PriceAudit{}
+	for key, a := range r.audits {
+		// the filtered tokens weren't published, there's nothing to audit
+		if !publishedKeys[key] {
+			delete(r.audits, key)
+			continue
+		}
+		if last, exist := r.lastAudited[key]; exist && last == a.UsdPrice {
+			continue
+		}
+		changed = append(changed, a)
+	}
+	if err := r.auditLog.InsertPriceAudits(ctx, changed); err != nil {
+		// keep the entries, they are retried with the next publish
+		log.Errorw("error when insert price audits", "audits", len(changed), "err", err)
+		return
+	}
+	for _, a := range changed {
+		r.lastAudited[auditKey(a.ChainID, a.TokenAddress)] = a.UsdPrice
+	}
+	r.audits = make(map[string]common.PriceAudit)
+}
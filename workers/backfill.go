@@ -0,0 +1,409 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+// BackfillStateName is the worker state holding the progress of the backfills from a block, a run to
+// a later block resumes the progress of the previous ones.
+func BackfillStateName(from, intervalBlocks int64) string {
+	return fmt.Sprintf("backfill:%d:%d", from, intervalBlocks)
+}
+
+type backfillState struct {
+	NextBlock int64 `json:"nextBlock"`
+}
+
+// Backfiller rebuilds the historical usd prices of the tokens from the trade logs against the anchors
+// and stores them as candles of intervalBlocks blocks.
+type Backfiller struct {
+	log            *zap.SugaredLogger
+	db             db.DB
+	store          db.CandleStore
+	anchors        []common.Anchor
+	intervalBlocks int64
+	chunkBlocks    int64
+	parallelism    int
+	metadataStore  db.MetadataStore
+}
+
+func NewBackfiller(log *zap.SugaredLogger, database db.DB, store db.CandleStore, anchors []common.Anchor,
+	intervalBlocks, chunkBlocks int64, parallelism int) *Backfiller {
+	// a chunk holds whole candles so chunks never write the same candle
+	if chunkBlocks < intervalBlocks {
+		chunkBlocks = intervalBlocks
+	}
+	chunkBlocks -= chunkBlocks % intervalBlocks
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return &Backfiller{
+		log:            log,
+		db:             database,
+		store:          store,
+		anchors:        anchors,
+		intervalBlocks: intervalBlocks,
+		chunkBlocks:    chunkBlocks,
+		parallelism:    parallelism,
+	}
+}
+
+// SetMetadataStore makes the backfill skip the trades whose amounts are far above the registered
+// total supply of their tokens, the amounts aren't in token units.
+func (b *Backfiller) SetMetadataStore(store db.MetadataStore) {
+	b.metadataStore = store
+}
+
+type blockRange struct {
+	from, to int64
+}
+
+type chunkResult struct {
+	index int
+	err   error
+}
+
+// Run backfills the candles of the blocks from..to, resuming after the last checkpoint from the same block.
+// from is aligned down to the candle interval so the first candle is complete.
+func (b *Backfiller) Run(ctx context.Context, from, to int64) error {
+	from -= from % b.intervalBlocks
+	name := BackfillStateName(from, b.intervalBlocks)
+	log := b.log.With("state", name)
+	start := from
+	data, err := b.db.GetWorkerState(ctx, name)
+	if err != nil {
+		log.Errorw("error when get backfill state", "err", err)
+		return err
+	}
+	if data != nil {
+		var state backfillState
+		if err := json.Unmarshal(data, &state); err != nil {
+			log.Errorw("error when unmarshal backfill state", "err", err)
+			return err
+		}
+		if state.NextBlock > to {
+			log.Infow("backfill already complete")
+			return nil
+		}
+		// a previous run to an earlier block may have stopped inside a candle, it's rebuilt whole
+		start = state.NextBlock - state.NextBlock%b.intervalBlocks
+		log.Infow("resume backfill", "block", start)
+	}
+
+	var chunks []blockRange
+	for bg := start; bg <= to; bg += b.chunkBlocks {
+		end := bg + b.chunkBlocks - 1
+		if end > to {
+			end = to
+		}
+		chunks = append(chunks, blockRange{from: bg, to: end})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan int)
+	results := make(chan chunkResult)
+	var wg sync.WaitGroup
+	for i := 0; i < b.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results <- chunkResult{index: index, err: b.backfillChunk(ctx, log, chunks[index])}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range chunks {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// the checkpoint only moves past contiguous finished chunks, so a resumed run redoes at most
+	// the chunks that were in flight
+	done := make([]bool, len(chunks))
+	next := 0
+	var firstErr error
+	for res := range results {
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+				cancel()
+			}
+			continue
+		}
+		done[res.index] = true
+		moved := false
+		for next < len(chunks) && done[next] {
+			next++
+			moved = true
+		}
+		if !moved || firstErr != nil {
+			continue
+		}
+		nextBlock := to + 1
+		if next < len(chunks) {
+			nextBlock = chunks[next].from
+		}
+		b.saveState(ctx, log, name, nextBlock)
+		log.Infow("backfill progress", "next_block", nextBlock, "chunks", next, "total", len(chunks))
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	log.Infow("finish backfill", "from", from, "to", to)
+	return nil
+}
+
+func (b *Backfiller) saveState(ctx context.Context, log *zap.SugaredLogger, name string, nextBlock int64) {
+	data, err := json.Marshal(backfillState{NextBlock: nextBlock})
+	if err != nil {
+		log.Errorw("error when marshal backfill state", "err", err)
+		return
+	}
+	if err := b.db.SaveWorkerState(ctx, name, data); err != nil {
+		log.Errorw("error when save backfill state", "err", err)
+	}
+}
+
+func (b *Backfiller) backfillChunk(ctx context.Context, log *zap.SugaredLogger, chunk blockRange) error {
+	anchors := make([]string, 0, len(b.anchors))
+	for _, a := range b.anchors {
+		anchors = append(anchors, a.Address)
+	}
+	trades, err := b.store.GetAnchorTradesByRange(ctx, db.BaseTradeLogs, chunk.from, chunk.to, anchors)
+	if err != nil {
+		log.Errorw("error when get anchor trades", "from", chunk.from, "to", chunk.to, "err", err)
+		return err
+	}
+	bounds, err := tradeAmountBounds(ctx, b.metadataStore, trades)
+	if err != nil {
+		log.Errorw("error when get token metadata", "from", chunk.from, "to", chunk.to, "err", err)
+		return err
+	}
+	candles := buildCandles(common.ChainBase.String(), trades, b.anchors, b.intervalBlocks, bounds)
+	if err := b.store.SaveCandles(ctx, candles); err != nil {
+		log.Errorw("error when save candles", "from", chunk.from, "to", chunk.to, "err", err)
+		return err
+	}
+	log.Debugw("backfill chunk", "from", chunk.from, "to", chunk.to, "trades", len(trades), "candles", len(candles))
+	return nil
+}
+
+// tradeAmountBounds returns the largest amount in token units of the traded tokens with a registered
+// total supply, supplyMaxOnChainRatio times the supply like the transfer logs supply check.
+func tradeAmountBounds(ctx context.Context, store db.MetadataStore, trades []common.Trade) (map[string]float64, error) {
+	if store == nil {
+		return nil, nil
+	}
+	seen := map[string]bool{}
+	tokens := []string{}
+	for _, t := range trades {
+		for _, a := range []string{t.TokenIn, t.TokenOut} {
+			if !seen[a] {
+				seen[a] = true
+				tokens = append(tokens, a)
+			}
+		}
+	}
+	bounds := map[string]float64{}
+	for bg := 0; bg < len(tokens); bg += registryLoadChunk {
+		end := bg + registryLoadChunk
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		metadata, err := store.GetTokenMetadata(ctx, common.ChainBase.String(), tokens[bg:end])
+		if err != nil {
+			return nil, err
+		}
+		for a, m := range metadata {
+			if m.TotalSupply != nil && *m.TotalSupply > 0 {
+				bounds[a] = *m.TotalSupply * supplyMaxOnChainRatio
+			}
+		}
+	}
+	return bounds, nil
+}
+
+// buildCandles prices every trade against an anchor and folds the prices into candles.
+// A non stable anchor is priced per candle by the vwap of its trades against the stables, carried
+// over candles without such trades, stables are worth $1. The trades with an amount above the bound
+// of its token aren't in token units and are skipped, a raw amount of an 18 decimals token is 1e18 too high.
+func buildCandles(chainID string, trades []common.Trade, anchors []common.Anchor, intervalBlocks int64,
+	bounds map[string]float64) []common.Candle {
+	stable := map[string]bool{}
+	isAnchor := map[string]bool{}
+	for _, a := range anchors {
+		address := strings.ToLower(a.Address)
+		isAnchor[address] = true
+		stable[address] = a.Stable
+	}
+	bucket := func(block int64) int64 {
+		return block - block%intervalBlocks
+	}
+	scaled := func(t common.Trade) bool {
+		if bound, exist := bounds[t.TokenIn]; exist && t.AmountIn > bound {
+			return false
+		}
+		if bound, exist := bounds[t.TokenOut]; exist && t.AmountOut > bound {
+			return false
+		}
+		return true
+	}
+	scaledTrades := make([]common.Trade, 0, len(trades))
+	for _, t := range trades {
+		if scaled(t) {
+			scaledTrades = append(scaledTrades, t)
+		}
+	}
+	trades = scaledTrades
+
+	type vwap struct {
+		usd, amount float64
+	}
+	volumes := map[string]map[int64]*vwap{}
+	buckets := map[int64]bool{}
+	for _, t := range trades {
+		buckets[bucket(t.BlockNumber)] = true
+		anchor, anchorAmount, stableAmount, ok := anchorStableSides(t, isAnchor, stable)
+		if !ok {
+			continue
+		}
+		if volumes[anchor] == nil {
+			volumes[anchor] = map[int64]*vwap{}
+		}
+		v := volumes[anchor][bucket(t.BlockNumber)]
+		if v == nil {
+			v = &vwap{}
+			volumes[anchor][bucket(t.BlockNumber)] = v
+		}
+		v.usd += stableAmount
+		v.amount += anchorAmount
+	}
+	sorted := make([]int64, 0, len(buckets))
+	for bg := range buckets {
+		sorted = append(sorted, bg)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	anchorPrices := map[string]map[int64]float64{}
+	for anchor, byBucket := range volumes {
+		prices := map[int64]float64{}
+		var last float64
+		for _, bg := range sorted {
+			if v := byBucket[bg]; v != nil && v.amount > 0 {
+				last = v.usd / v.amount
+			}
+			prices[bg] = last
+		}
+		// candles before the first stable trade of the range take its price
+		for i := len(sorted) - 1; i >= 0; i-- {
+			if prices[sorted[i]] > 0 {
+				last = prices[sorted[i]]
+				continue
+			}
+			prices[sorted[i]] = last
+		}
+		anchorPrices[anchor] = prices
+	}
+	anchorUsd := func(anchor string, bg int64) float64 {
+		if stable[anchor] {
+			return 1
+		}
+		return anchorPrices[anchor][bg]
+	}
+
+	candles := map[string]map[int64]*common.Candle{}
+	var result []*common.Candle
+	for _, t := range trades {
+		var token, anchor string
+		var tokenAmount, anchorAmount float64
+		switch {
+		case isAnchor[t.TokenIn] && isAnchor[t.TokenOut]:
+			// anchors get candles of their trades against the stables
+			a, aAmount, sAmount, ok := anchorStableSides(t, isAnchor, stable)
+			if !ok {
+				continue
+			}
+			token, tokenAmount = a, aAmount
+			anchorAmount = sAmount
+			if t.TokenIn == a {
+				anchor = t.TokenOut
+			} else {
+				anchor = t.TokenIn
+			}
+		case isAnchor[t.TokenIn]:
+			token, tokenAmount, anchor, anchorAmount = t.TokenOut, t.AmountOut, t.TokenIn, t.AmountIn
+		case isAnchor[t.TokenOut]:
+			token, tokenAmount, anchor, anchorAmount = t.TokenIn, t.AmountIn, t.TokenOut, t.AmountOut
+		default:
+			continue
+		}
+		bg := bucket(t.BlockNumber)
+		usd := anchorUsd(anchor, bg)
+		if tokenAmount <= 0 || anchorAmount <= 0 || usd <= 0 {
+			continue
+		}
+		price := anchorAmount * usd / tokenAmount
+		if candles[token] == nil {
+			candles[token] = map[int64]*common.Candle{}
+		}
+		c := candles[token][bg]
+		if c == nil {
+			c = &common.Candle{
+				ChainID:        chainID,
+				TokenAddress:   token,
+				IntervalBlocks: intervalBlocks,
+				StartBlock:     bg,
+				Open:           price,
+				High:           price,
+				Low:            price,
+			}
+			candles[token][bg] = c
+			result = append(result, c)
+		}
+		if price > c.High {
+			c.High = price
+		}
+		if price < c.Low {
+			c.Low = price
+		}
+		c.Close = price
+		c.VolumeUsd += anchorAmount * usd
+		c.Trades++
+	}
+
+	out := make([]common.Candle, 0, len(result))
+	for _, c := range result {
+		out = append(out, *c)
+	}
+	return out
+}
+
+// anchorStableSides returns the non stable anchor of a trade against a stable with both amounts.
+func anchorStableSides(t common.Trade, isAnchor, stable map[string]bool) (string, float64, float64, bool) {
+	if !isAnchor[t.TokenIn] || !isAnchor[t.TokenOut] || stable[t.TokenIn] == stable[t.TokenOut] {
+		return "", 0, 0, false
+	}
+	if stable[t.TokenIn] {
+		return t.TokenOut, t.AmountOut, t.AmountIn, true
+	}
+	return t.TokenIn, t.AmountIn, t.AmountOut, true
+}
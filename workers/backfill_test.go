@@ -0,0 +1,50 @@
+package workers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+func TestBuildCandles(t *testing.T) {
+	const weth = "0x4200000000000000000000000000000000000006"
+	const usdc = "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913"
+	const token = "0x000000000000000000000000000000000000000a"
+	anchors := []common.Anchor{
+		{Symbol: "WETH", Address: weth},
+		{Symbol: "USDC", Address: "0x833589FCD6EDB6E08F4C7C32D4F71B54BDA02913", Stable: true},
+	}
+	trades := []common.Trade{
+		// before the first stable trade weth takes the price of the first candle with one
+		{BlockNumber: 95, TokenIn: weth, TokenOut: token, AmountIn: 1, AmountOut: 50},
+		// the weth vwap of the candle is 5000 / 2
+		{BlockNumber: 100, TokenIn: usdc, TokenOut: weth, AmountIn: 2000, AmountOut: 1},
+		{BlockNumber: 101, TokenIn: weth, TokenOut: usdc, AmountIn: 1, AmountOut: 3000},
+		{BlockNumber: 102, TokenIn: weth, TokenOut: token, AmountIn: 1, AmountOut: 100},
+		{BlockNumber: 103, TokenIn: token, TokenOut: weth, AmountIn: 50, AmountOut: 1},
+		// a raw amount above the bound of the token is skipped
+		{BlockNumber: 104, TokenIn: usdc, TokenOut: token, AmountIn: 10, AmountOut: 1e20},
+		{BlockNumber: 105, TokenIn: usdc, TokenOut: token, AmountIn: 10, AmountOut: 1},
+		// weth carries its last price into the candle without stable trades
+		{BlockNumber: 112, TokenIn: token, TokenOut: weth, AmountIn: 125, AmountOut: 1},
+		// neither side is an anchor
+		{BlockNumber: 113, TokenIn: token, TokenOut: "0x000000000000000000000000000000000000000b", AmountIn: 1, AmountOut: 1},
+	}
+	bounds := map[string]float64{token: 1e6}
+
+	candle := func(address string, start int64, open, high, low, close, volume float64, trades int64) common.Candle {
+		return common.Candle{ChainID: "base", TokenAddress: address, IntervalBlocks: 10, StartBlock: start,
+			Open: open, High: high, Low: low, Close: close, VolumeUsd: volume, Trades: trades}
+	}
+	want := []common.Candle{
+		candle(token, 90, 50, 50, 50, 50, 2500, 1),
+		candle(weth, 100, 2000, 3000, 2000, 3000, 5000, 2),
+		candle(token, 100, 25, 50, 10, 10, 5010, 3),
+		candle(token, 110, 20, 20, 20, 20, 2500, 1),
+	}
+	got := buildCandles("base", trades, anchors, 10, bounds)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildCandles =\n%+v\nwant\n%+v", got, want)
+	}
+}
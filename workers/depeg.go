@@ -0,0 +1,194 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/rateprovider"
+	"github.com/kv-base-hack/base-token-rate/lib/tracing"
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
+	"go.uber.org/zap"
+)
+
+const stablecoinStatusKey = "stablecoin_depeg_status"
+
+// deviation from $1 in percent for each severity
+const depegMinorDeviation = 1
+const depegMajorDeviation = 3
+const depegCriticalDeviation = 10
+
+// DepegMonitor tracks the price of the stablecoins used as quote tokens on cex and dex,
+// so the dex prices quoted through a stablecoin off its peg can be flagged.
+type DepegMonitor struct {
+	stablecoins  []common.Stablecoin
+	rateProvider rateprovider.RateProvider
+	inMemDB      kv.Store
+
+	mu     sync.RWMutex
+	status map[string]common.StablecoinStatus
+}
+
+func NewDepegMonitor(stablecoins []common.Stablecoin, rateProvider rateprovider.RateProvider, inMemDB kv.Store) *DepegMonitor {
+	return &DepegMonitor{
+		stablecoins:  stablecoins,
+		rateProvider: rateProvider,
+		inMemDB:      inMemDB,
+		status:       make(map[string]common.StablecoinStatus),
+	}
+}
+
+func severityOf(deviation float64) common.DepegSeverity {
+	d := math.Abs(deviation)
+	switch {
+	case d >= depegCriticalDeviation:
+		return common.DepegSeverityCritical
+	case d >= depegMajorDeviation:
+		return common.DepegSeverityMajor
+	case d >= depegMinorDeviation:
+		return common.DepegSeverityMinor
+	default:
+		return common.DepegSeverityNone
+	}
+}
+
+// dexPrices returns the liquidity weighted dex price of each stablecoin.
+func (m *DepegMonitor) dexPrices(ctx context.Context, log *zap.SugaredLogger) map[string]float64 {
+	addresses := make([]string, 0, len(m.stablecoins))
+	for _, s := range m.stablecoins {
+		addresses = append(addresses, s.Address)
+	}
+	_, span := tracing.Tracer().Start(ctx, "dexscreener.stablecoins")
+	pairs, err := m.rateProvider.GetPrices(strings.Join(addresses, ","))
+	tracing.EndSpan(span, err)
+	if err != nil {
+		log.Errorw("error when get stablecoin dex prices", "err", err)
+		return map[string]float64{}
+	}
+
+	weighted := map[string]float64{}
+	liquidity := map[string]float64{}
+	for _, p := range pairs.Pairs {
+		if p.Liquidity.Usd < minLiquidity || p.PriceUsd == 0 {
+			continue
+		}
+		address := strings.ToLower(p.BaseToken.Address)
+		weighted[address] += p.PriceUsd * p.Liquidity.Usd
+		liquidity[address] += p.Liquidity.Usd
+	}
+	prices := make(map[string]float64, len(weighted))
+	for a, w := range weighted {
+		prices[a] = w / liquidity[a]
+	}
+	return prices
+}
+
+// Update refreshes the depeg status from the cex rates against USDT and the dex pairs,
+// it returns the number of rate provider requests it made.
+func (m *DepegMonitor) Update(ctx context.Context, log *zap.SugaredLogger, cexRates map[string]float64) int {
+	if len(m.stablecoins) == 0 {
+		return 0
+	}
+	dexPrices := m.dexPrices(ctx, log)
+	status := make(map[string]common.StablecoinStatus, len(m.stablecoins))
+	published := make([]common.StablecoinStatus, 0, len(m.stablecoins))
+	for _, s := range m.stablecoins {
+		address := strings.ToLower(s.Address)
+		st := common.StablecoinStatus{
+			Symbol:   s.Symbol,
+			Address:  s.Address,
+			ChainID:  s.ChainID,
+			CexPrice: cexRates[s.CexSymbol],
+			DexPrice: dexPrices[address],
+		}
+		for _, price := range []float64{st.CexPrice, st.DexPrice} {
+			if price == 0 {
+				continue
+			}
+			if deviation := (price - 1) * 100; math.Abs(deviation) > math.Abs(st.Deviation) {
+				st.Deviation = deviation
+			}
+		}
+		st.Severity = severityOf(st.Deviation)
+		if st.Severity != common.DepegSeverityNone {
+			log.Warnw("stablecoin off peg", "status", st)
+		}
+		status[address] = st
+		published = append(published, st)
+	}
+
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+
+	data, err := json.Marshal(common.RedisStablecoinStatus{
+		UpdatedTime: time.Now().Unix(),
+		Stablecoins: published,
+	})
+	if err != nil {
+		log.Errorw("error when marshal data", "err", err)
+		return 1
+	}
+	// no expire
+	if err := m.inMemDB.Set(stablecoinStatusKey, data, 0); err != nil {
+		log.Errorw("error when set key", "key", stablecoinStatusKey, "err", err)
+	}
+	return 1
+}
+
+// Load sets the depeg status the leader published, the shard replicas don't query the stablecoins.
+func (m *DepegMonitor) Load(ctx context.Context, log *zap.SugaredLogger) {
+	data, err := m.inMemDB.Get(ctx, stablecoinStatusKey)
+	if err != nil {
+		log.Errorw("error when get key", "key", stablecoinStatusKey, "err", err)
+		return
+	}
+	var published common.RedisStablecoinStatus
+	if err := json.Unmarshal(data, &published); err != nil {
+		log.Errorw("error when unmarshal data", "key", stablecoinStatusKey, "err", err)
+		return
+	}
+	status := make(map[string]common.StablecoinStatus, len(published.Stablecoins))
+	for _, st := range published.Stablecoins {
+		status[strings.ToLower(st.Address)] = st
+	}
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+}
+
+// Depegged reports whether the token is a stablecoin currently off its peg.
+func (m *DepegMonitor) Depegged(address string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	st, exist := m.status[strings.ToLower(address)]
+	return exist && st.Severity != common.DepegSeverityNone
+}
+
+// Price returns the monitored price of a stablecoin, the cex price or else the dex price,
+// false if it isn't monitored or has no price.
+func (m *DepegMonitor) Price(address string) (float64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	st, exist := m.status[strings.ToLower(address)]
+	if !exist {
+		return 0, false
+	}
+	if st.CexPrice > 0 {
+		return st.CexPrice, true
+	}
+	return st.DexPrice, st.DexPrice > 0
+}
+
+// flagQuoteDepeg returns the token with the quote depeg flag set if it's priced through a depegged stablecoin.
+func (m *DepegMonitor) flagQuoteDepeg(t common.Token) common.Token {
+	if t.QuoteTokenAddress == "" || !m.Depegged(t.QuoteTokenAddress) {
+		return t
+	}
+	t.Flags = append(append([]string{}, t.Flags...), common.TokenFlagQuoteDepeg)
+	return t
+}
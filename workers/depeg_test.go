@@ -0,0 +1,109 @@
+package workers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
+	"go.uber.org/zap"
+)
+
+type fakeRateProvider struct {
+	pairs []common.Pair
+}
+
+func (f fakeRateProvider) GetPrices(string) (common.Pairs, error) {
+	return common.Pairs{Pairs: f.pairs}, nil
+}
+
+func stablecoinPair(address string, price, liquidity float64) common.Pair {
+	p := common.Pair{PriceUsd: price, BaseToken: common.PairToken{Address: address}}
+	p.Liquidity.Usd = liquidity
+	return p
+}
+
+func TestSeverityOf(t *testing.T) {
+	tests := []struct {
+		deviation float64
+		want      common.DepegSeverity
+	}{
+		{0, common.DepegSeverityNone},
+		{0.99, common.DepegSeverityNone},
+		{-0.5, common.DepegSeverityNone},
+		{depegMinorDeviation, common.DepegSeverityMinor},
+		{-2.5, common.DepegSeverityMinor},
+		{depegMajorDeviation, common.DepegSeverityMajor},
+		{-9.99, common.DepegSeverityMajor},
+		{depegCriticalDeviation, common.DepegSeverityCritical},
+		{-50, common.DepegSeverityCritical},
+	}
+	for _, tt := range tests {
+		if got := severityOf(tt.deviation); got != tt.want {
+			t.Errorf("severityOf(%v) = %s, want %s", tt.deviation, got, tt.want)
+		}
+	}
+}
+
+func TestDepegMonitor(t *testing.T) {
+	const usdc = "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913"
+	const usdbc = "0xd9aaec86b65d86f6a7b5b1b0c42ffa531710b6ca"
+	const dai = "0x50c5725949a6f0c72e6c4a641f24049a917db0cb"
+	const usdt = "0xfde4c96c8593536e31f229ea8f37b2ada2699bb2"
+	provider := fakeRateProvider{pairs: []common.Pair{
+		stablecoinPair("0x833589FCD6EDB6E08F4C7C32D4F71B54BDA02913", 1.001, minLiquidity),
+		// the liquidity weighted price of usdbc is 0.95
+		stablecoinPair(usdbc, 0.94, 3*minLiquidity),
+		stablecoinPair(usdbc, 0.98, minLiquidity),
+		// below the min liquidity
+		stablecoinPair(usdbc, 0.5, minLiquidity-1),
+		stablecoinPair(dai, 1.0, minLiquidity),
+	}}
+	store := kv.NewMemory()
+	log := zap.NewNop().Sugar()
+	m := NewDepegMonitor(common.BaseStablecoins, provider, store)
+	// the cex deviation of dai is the worst of its prices
+	requests := m.Update(context.Background(), log, map[string]float64{"USDCUSDT": 0.9995, "DAIUSDT": 0.88})
+	if requests != 1 {
+		t.Fatalf("Update made %d requests, want 1", requests)
+	}
+
+	replica := NewDepegMonitor(common.BaseStablecoins, provider, store)
+	replica.Load(context.Background(), log)
+
+	tests := []struct {
+		name     string
+		address  string
+		depegged bool
+		price    float64
+		priced   bool
+	}{
+		{"usdc on peg", usdc, false, 0.9995, true},
+		{"usdbc priced from the dex", usdbc, true, 0.95, true},
+		{"dai off peg on cex", dai, true, 0.88, true},
+		{"usdt without price", usdt, false, 0, false},
+		{"not a stablecoin", "0x4200000000000000000000000000000000000006", false, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, monitor := range map[string]*DepegMonitor{"leader": m, "replica": replica} {
+				if got := monitor.Depegged(tt.address); got != tt.depegged {
+					t.Errorf("%s: Depegged = %v, want %v", name, got, tt.depegged)
+				}
+				price, priced := monitor.Price(tt.address)
+				if priced != tt.priced || !approxEqual(price, tt.price) {
+					t.Errorf("%s: Price = %v, %v, want %v, %v", name, price, priced, tt.price, tt.priced)
+				}
+				flagged := monitor.flagQuoteDepeg(common.Token{QuoteTokenAddress: tt.address})
+				if got := len(flagged.Flags) == 1 && flagged.Flags[0] == common.TokenFlagQuoteDepeg; got != tt.depegged {
+					t.Errorf("%s: quote depeg flag %v, want %v", name, flagged.Flags, tt.depegged)
+				}
+			}
+		})
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	const epsilon = 1e-9
+	return a-b < epsilon && b-a < epsilon
+}
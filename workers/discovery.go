@@ -0,0 +1,154 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DiscoveryStreamKey is the redis stream of the discovery events, the event is in the "data" field as json.
+const DiscoveryStreamKey = "token_discoveries"
+
+// the stream keeps about the last discoveryStreamMaxLen events
+const discoveryStreamMaxLen = 10000
+const discoveryChunk = 1000
+
+// the discoveries not priced within discoveryPriceWindow aren't waited for anymore
+const discoveryPriceWindow = 7 * 24 * time.Hour
+
+type DiscoveryPublisher interface {
+	Publish(ctx context.Context, event common.DiscoveryEvent) error
+}
+
+type RedisDiscoveryStream struct {
+	client *redis.Client
+}
+
+func NewRedisDiscoveryStream(client *redis.Client) *RedisDiscoveryStream {
+	return &RedisDiscoveryStream{client: client}
+}
+
+func (s *RedisDiscoveryStream) Publish(ctx context.Context, event common.DiscoveryEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: DiscoveryStreamKey,
+		MaxLen: discoveryStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": event.Event, "data": string(data)},
+	}).Err()
+}
+
+// SetDiscoveryFeed makes the worker record the tokens it sees for the first time and their first price.
+func (r *RateWorker) SetDiscoveryFeed(feed db.DiscoveryFeed, publisher DiscoveryPublisher) {
+	r.discoveryFeed = feed
+	r.discoveryPublisher = publisher
+}
+
+func (r *RateWorker) publishDiscovery(ctx context.Context, log *zap.SugaredLogger, event string, d common.TokenDiscovery) {
+	if r.discoveryPublisher == nil {
+		return
+	}
+	if err := r.discoveryPublisher.Publish(ctx, common.DiscoveryEvent{Event: event, Discovery: d}); err != nil {
+		log.Errorw("error when publish discovery", "event", event, "address", d.Address, "err", err)
+	}
+}
+
+// recordDiscoveries stores the new addresses seen up to block, the ones stored before aren't new.
+func (r *RateWorker) recordDiscoveries(ctx context.Context, log *zap.SugaredLogger, addresses []string, block int64) {
+	if r.discoveryFeed == nil {
+		return
+	}
+	for bg := 0; bg < len(addresses); bg += discoveryChunk {
+		end := bg + discoveryChunk
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		r.recordDiscoveryChunk(ctx, log, addresses[bg:end], block)
+	}
+}
+
+func (r *RateWorker) recordDiscoveryChunk(ctx context.Context, log *zap.SugaredLogger, addresses []string, block int64) {
+	firstSeen, err := r.discoveryFeed.GetTokenFirstSeen(ctx, db.BaseTransferLogs, addresses)
+	if err != nil {
+		log.Errorw("error when get token first seen", "err", err)
+		return
+	}
+	firstTrade, err := r.discoveryFeed.GetTokenFirstTrade(ctx, db.BaseTradeLogs, addresses)
+	if err != nil {
+		log.Errorw("error when get token first trade", "err", err)
+		return
+	}
+	now := time.Now()
+	discoveries := make([]common.TokenDiscovery, 0, len(addresses))
+	for _, a := range addresses {
+		d := common.TokenDiscovery{
+			ChainID:        common.ChainBase.String(),
+			Address:        a,
+			FirstSeenBlock: block,
+			FirstSeenAt:    now,
+		}
+		if f, exist := firstSeen[a]; exist {
+			d.FirstSeenBlock, d.FirstMinter = f.Block, f.FirstMinter
+		}
+		if b, exist := firstTrade[a]; exist {
+			d.FirstTradeBlock = &b
+			if b < d.FirstSeenBlock {
+				d.FirstSeenBlock = b
+			}
+		}
+		discoveries = append(discoveries, d)
+	}
+	inserted, err := r.discoveryFeed.InsertTokenDiscoveries(ctx, discoveries)
+	if err != nil {
+		log.Errorw("error when insert token discoveries", "err", err)
+		return
+	}
+	for _, d := range inserted {
+		r.unpriced[d.Address] = true
+		r.publishDiscovery(ctx, log, common.DiscoveryEventDiscovered, d)
+	}
+	log.Infow("finish record discoveries", "addresses", len(addresses), "new", len(inserted))
+}
+
+// recordFirstPrice stores the first price and liquidity of a discovered token.
+func (r *RateWorker) recordFirstPrice(ctx context.Context, log *zap.SugaredLogger, address string, p common.Pair, at time.Time) {
+	if r.discoveryFeed == nil || !r.unpriced[address] {
+		return
+	}
+	d, err := r.discoveryFeed.SetTokenDiscoveryPriced(ctx, common.ChainBase.String(), address, p.PriceUsd, p.Liquidity.Usd, at)
+	if err != nil {
+		// retried with the next price
+		log.Errorw("error when set token discovery priced", "address", address, "err", err)
+		return
+	}
+	delete(r.unpriced, address)
+	if d != nil {
+		r.publishDiscovery(ctx, log, common.DiscoveryEventPriced, *d)
+	}
+}
+
+// loadUnpriced loads the recent discoveries waiting for their first price, they aren't lost on a restart
+// and the shard replicas get the ones the leader discovered.
+func (r *RateWorker) loadUnpriced(ctx context.Context, log *zap.SugaredLogger) {
+	if r.discoveryFeed == nil {
+		return
+	}
+	addresses, err := r.discoveryFeed.GetUnpricedTokenDiscoveries(ctx, common.ChainBase.String(), time.Now().Add(-discoveryPriceWindow))
+	if err != nil {
+		log.Errorw("error when get unpriced token discoveries", "err", err)
+		return
+	}
+	unpriced := make(map[string]bool, len(addresses))
+	for _, a := range addresses {
+		unpriced[a] = true
+	}
+	r.unpriced = unpriced
+}
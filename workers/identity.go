@@ -0,0 +1,216 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
+	"go.uber.org/zap"
+)
+
+type identityIndex struct {
+	loadedAt time.Time
+	tokens   map[string]common.RedisTokenMetadata
+	// bySymbol has the lowercase addresses of the listed tokens of a symbol
+	bySymbol map[string][]string
+}
+
+// IdentityResolver resolves the canonical identity of tokens from the coinmarketcap and coingecko
+// address indexes published by the TokenInfoWorker, the indexes of a chain are reloaded every ttl.
+type IdentityResolver struct {
+	log   *zap.SugaredLogger
+	store kv.Store
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	indexes map[string]*identityIndex
+}
+
+func NewIdentityResolver(log *zap.SugaredLogger, store kv.Store, ttl time.Duration) *IdentityResolver {
+	return &IdentityResolver{
+		log:     log,
+		store:   store,
+		ttl:     ttl,
+		indexes: make(map[string]*identityIndex),
+	}
+}
+
+func (r *IdentityResolver) index(ctx context.Context, chain string) *identityIndex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx, exist := r.indexes[chain]
+	if exist && time.Since(idx.loadedAt) < r.ttl {
+		return idx
+	}
+	if !exist {
+		idx = &identityIndex{}
+		r.indexes[chain] = idx
+	}
+	// a failed load keeps the previous index until the next ttl
+	idx.loadedAt = time.Now()
+	var tokenIndex common.RedisTokenIndex
+	var coingeckoIndex common.RedisCoingeckoIndex
+	cmcLoaded := r.load(ctx, CmcTokenIndexKey(chain), &tokenIndex)
+	coingeckoLoaded := r.load(ctx, CoingeckoTokenIndexKey(chain), &coingeckoIndex)
+	if !cmcLoaded && !coingeckoLoaded {
+		return idx
+	}
+	tokens := tokenIndex.Tokens
+	if tokens == nil {
+		tokens = map[string]common.RedisTokenMetadata{}
+	}
+	// the tokens only coingecko lists
+	if coingeckoLoaded {
+		for address, coin := range coingeckoIndex.Tokens {
+			if _, exist := tokens[address]; exist {
+				continue
+			}
+			tokens[address] = common.RedisTokenMetadata{
+				RedisTokenInfo: common.RedisTokenInfo{
+					Symbol:       strings.ToUpper(coin.Symbol),
+					Name:         coin.Name,
+					Platform:     chain,
+					TokenAddress: address,
+				},
+				CoingeckoID: coin.ID,
+			}
+		}
+	}
+	idx.tokens = tokens
+	idx.bySymbol = make(map[string][]string)
+	for address, t := range tokens {
+		symbol := strings.ToLower(t.Symbol)
+		idx.bySymbol[symbol] = append(idx.bySymbol[symbol], address)
+	}
+	return idx
+}
+
+// load reads an index into v, it returns false if the index isn't published or can't be read.
+func (r *IdentityResolver) load(ctx context.Context, key string, v interface{}) bool {
+	data, err := r.store.Get(ctx, key)
+	if errors.Is(err, kv.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		r.log.Errorw("error when get key", "key", key, "err", err)
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		r.log.Errorw("error when unmarshal token index", "key", key, "err", err)
+		return false
+	}
+	return true
+}
+
+// Identity returns the identity of the token at the address, false if no index lists it.
+func (r *IdentityResolver) Identity(ctx context.Context, chainID, address string) (common.TokenIdentity, bool) {
+	t, exist := r.index(ctx, chainID).tokens[strings.ToLower(address)]
+	if !exist {
+		return common.TokenIdentity{ChainID: chainID, Address: address}, false
+	}
+	return common.TokenIdentity{
+		ChainID:     chainID,
+		Address:     address,
+		Symbol:      t.Symbol,
+		Name:        t.Name,
+		CmcID:       t.ID,
+		CoingeckoID: t.CoingeckoID,
+	}, true
+}
+
+// Resolve sets the cross references of the token.
+func (r *IdentityResolver) Resolve(ctx context.Context, t common.Token) common.Token {
+	identity, _ := r.Identity(ctx, t.ChainID, t.Address)
+	t.CmcID, t.CoingeckoID = identity.CmcID, identity.CoingeckoID
+	return t
+}
+
+// Search returns the tokens of the snapshot and of the index whose symbol is the symbol, ranked so
+// that listed tokens come first and unlisted tokens borrowing the symbol of a listed one come last.
+func (r *IdentityResolver) Search(ctx context.Context, symbol string, snapshot []common.Token) []common.TokenCandidate {
+	lowerSymbol := strings.ToLower(symbol)
+	candidates := map[string]common.TokenCandidate{}
+	chains := map[string]bool{}
+	for _, t := range snapshot {
+		chains[t.ChainID] = true
+		if strings.ToLower(t.Symbol) != lowerSymbol {
+			continue
+		}
+		identity, _ := r.Identity(ctx, t.ChainID, t.Address)
+		if identity.Symbol == "" {
+			identity.Symbol = t.Symbol
+		}
+		c := common.TokenCandidate{
+			TokenIdentity: identity,
+			UsdPrice:      t.UsdPrice,
+			SourcePrice:   t.SourcePrice.String(),
+			LiquidityUsd:  t.LiquidityUsd,
+		}
+		// a cex and a dex price of the same token, keep the cex one
+		if existing, exist := candidates[identity.ID()]; exist && existing.SourcePrice == common.SourcePriceCex.String() {
+			continue
+		}
+		candidates[identity.ID()] = c
+	}
+
+	listed := false
+	for chain := range chains {
+		idx := r.index(ctx, chain)
+		for _, address := range idx.bySymbol[lowerSymbol] {
+			listed = true
+			identity, _ := r.Identity(ctx, chain, address)
+			if _, exist := candidates[identity.ID()]; exist {
+				continue
+			}
+			candidates[identity.ID()] = common.TokenCandidate{
+				TokenIdentity: identity,
+				UsdPrice:      idx.tokens[address].UsdPrice,
+			}
+		}
+	}
+
+	result := make([]common.TokenCandidate, 0, len(candidates))
+	scores := map[string]int{}
+	for id, c := range candidates {
+		c.Flags = []string{}
+		score := 0
+		if c.CmcID != 0 {
+			c.Flags = append(c.Flags, common.TokenFlagCmcListed)
+			score += 4
+		}
+		if c.CoingeckoID != "" {
+			c.Flags = append(c.Flags, common.TokenFlagCoingeckoListed)
+			score += 2
+		}
+		if c.SourcePrice == common.SourcePriceCex.String() {
+			c.Flags = append(c.Flags, common.TokenFlagCexListed)
+			score += 2
+		}
+		if score == 0 && listed {
+			c.Flags = append(c.Flags, common.TokenFlagSymbolCollision)
+			score--
+		}
+		scores[id] = score
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		si, sj := scores[result[i].ID()], scores[result[j].ID()]
+		if si != sj {
+			return si > sj
+		}
+		if result[i].LiquidityUsd != result[j].LiquidityUsd {
+			return result[i].LiquidityUsd > result[j].LiquidityUsd
+		}
+		return result[i].ID() < result[j].ID()
+	})
+	for i := range result {
+		result[i].Rank = i + 1
+	}
+	return result
+}
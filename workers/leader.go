@@ -0,0 +1,30 @@
+package workers
+
+import (
+	"context"
+	"time"
+)
+
+// Leadership reports whether this replica should run the workers, the others stay as hot standbys.
+type Leadership interface {
+	IsLeader() bool
+	// Context is cancelled when this replica loses leadership, so a cycle in flight stops writing.
+	Context() context.Context
+	// LeaderSince is when the leadership was acquired, zero if the replica is always leader.
+	LeaderSince() time.Time
+}
+
+// AlwaysLeader is used when a single replica is deployed.
+type AlwaysLeader struct{}
+
+func (AlwaysLeader) IsLeader() bool {
+	return true
+}
+
+func (AlwaysLeader) Context() context.Context {
+	return context.Background()
+}
+
+func (AlwaysLeader) LeaderSince() time.Time {
+	return time.Time{}
+}
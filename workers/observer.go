@@ -0,0 +1,28 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// SnapshotObserver is called with every published snapshot, e.g. to evaluate alert rules.
+type SnapshotObserver interface {
+	OnSnapshot(ctx context.Context, tokens []common.Token, at time.Time)
+}
+
+func (r *RateWorker) AddObserver(observer SnapshotObserver) {
+	r.observers = append(r.observers, observer)
+}
+
+func (m *SnapshotMerger) AddObserver(observer SnapshotObserver) {
+	m.observers = append(m.observers, observer)
+}
+
+func notifyObservers(ctx context.Context, observers []SnapshotObserver, tokens []common.Token) {
+	now := time.Now()
+	for _, o := range observers {
+		o.OnSnapshot(ctx, tokens, now)
+	}
+}
@@ -0,0 +1,306 @@
+package workers
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/erc20"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+// max number of rpc reads per cycle to register pools and to price tokens from pools
+const poolRegistryBatch = 200
+const poolPriceBatch = 200
+const poolTokensChunk = 500
+
+// SetPoolRegistry makes the worker register the pools of the trade logs and price the tracked tokens
+// without a dex price from their pools against the anchors, only the pools of the factories are registered.
+func (r *RateWorker) SetPoolRegistry(registry db.PoolRegistry, rpc *erc20.Client, anchors []common.Anchor, factories []string) {
+	r.poolRegistry = registry
+	r.poolRPC = rpc
+	r.anchors = make(map[string]common.Anchor, len(anchors))
+	for _, a := range anchors {
+		r.anchors[strings.ToLower(a.Address)] = a
+	}
+	r.poolFactories = make(map[string]bool, len(factories))
+	for _, f := range factories {
+		r.poolFactories[strings.ToLower(f)] = true
+	}
+}
+
+// toUnits converts a raw amount to token units, 0 if the decimals aren't a uint8.
+func toUnits(raw *big.Int, decimals int) float64 {
+	if decimals < 0 || decimals > erc20.MaxDecimals {
+		return 0
+	}
+	value := new(big.Float).SetInt(raw)
+	unit := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	result, _ := value.Quo(value, unit).Float64()
+	return result
+}
+
+// price0 returns the price of token0 in token1 from the reserves or the sqrt price.
+func price0(state erc20.PoolState, decimals0, decimals1 int) float64 {
+	switch state.Kind {
+	case common.PoolKindV2:
+		reserve0 := toUnits(state.Reserve0, decimals0)
+		if reserve0 == 0 {
+			return 0
+		}
+		return toUnits(state.Reserve1, decimals1) / reserve0
+	case common.PoolKindV3:
+		sqrtPrice := new(big.Float).SetInt(state.SqrtPriceX96)
+		sqrtPrice.Quo(sqrtPrice, new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 96)))
+		price, _ := new(big.Float).Mul(sqrtPrice, sqrtPrice).Float64()
+		return price * math.Pow10(decimals0-decimals1)
+	default:
+		return 0
+	}
+}
+
+// updateAnchorRates sets the usd rate of the anchors, the shard replicas get them from the leader state.
+func (r *RateWorker) updateAnchorRates(cexRates map[string]float64) {
+	rates := make(map[string]float64, len(r.anchors))
+	for a, anchor := range r.anchors {
+		if usd := r.anchorUsd(anchor, cexRates); usd > 0 {
+			rates[a] = usd
+		}
+	}
+	r.chainData[common.ChainBase].anchorRates = rates
+}
+
+func (r *RateWorker) anchorUsd(anchor common.Anchor, cexRates map[string]float64) float64 {
+	if rate, exist := cexRates[anchor.CexSymbol]; exist && anchor.CexSymbol != "" {
+		return rate
+	}
+	if anchor.Stable {
+		// a stablecoin without a cex rate is worth its monitored price, $1 until it's known
+		if price, exist := r.depeg.Price(anchor.Address); exist {
+			return price
+		}
+		return 1
+	}
+	return 0
+}
+
+// tokenDecimals returns the decimals of a token from the registry or the rpc.
+func (r *RateWorker) tokenDecimals(ctx context.Context, address string) (int, bool) {
+	if m, exist := r.metadata[address]; exist && m.Decimals != nil && *m.Decimals >= 0 && *m.Decimals <= erc20.MaxDecimals {
+		return *m.Decimals, true
+	}
+	if d, exist := r.decimals[address]; exist {
+		return d, true
+	}
+	onChain, err := r.poolRPC.GetMetadata(ctx, address)
+	if err != nil || onChain.Decimals == nil || *onChain.Decimals < 0 || *onChain.Decimals > erc20.MaxDecimals {
+		return 0, false
+	}
+	r.decimals[address] = *onChain.Decimals
+	return *onChain.Decimals, true
+}
+
+// updatePools registers the pools traded since the last scan and prices the tokens without a dex price.
+// The pools are registered by the leader only.
+func (r *RateWorker) updatePools(ctx context.Context, log *zap.SugaredLogger, leader bool) {
+	if r.poolRegistry == nil || r.poolRPC == nil {
+		return
+	}
+	if leader {
+		r.registerPools(ctx, log)
+	}
+	r.priceFromPools(ctx, log)
+}
+
+func (r *RateWorker) registerPools(ctx context.Context, log *zap.SugaredLogger) {
+	chainID := common.ChainBase.String()
+	last := r.chainData[common.ChainBase].lastStoredBlock
+	from := r.poolsScannedBlock + 1
+	if from < last-maxBlockRange {
+		from = last - maxBlockRange
+	}
+	if from <= last {
+		addresses, err := r.poolRegistry.GetPoolAddressesByRange(ctx, db.BaseTradeLogs, from, last)
+		if err != nil {
+			log.Errorw("error when get pool addresses by range", "from", from, "to", last, "err", err)
+			return
+		}
+		r.pendingPools = append(r.pendingPools, addresses...)
+		r.poolsScannedBlock = last
+	}
+	if len(r.pendingPools) == 0 {
+		return
+	}
+	known, err := r.poolRegistry.GetPools(ctx, chainID, r.pendingPools)
+	if err != nil {
+		log.Errorw("error when get pools", "err", err)
+		return
+	}
+	pending := []string{}
+	pools := []common.LiquidityPool{}
+	for _, a := range r.pendingPools {
+		if _, exist := known[a]; exist || a == "" {
+			continue
+		}
+		if len(pools) >= poolRegistryBatch {
+			pending = append(pending, a)
+			continue
+		}
+		state, err := r.poolRPC.GetPoolState(ctx, a)
+		if err != nil {
+			// not a v2 or v3 pool, or the rpc failed, it's retried when traded again
+			log.Debugw("error when get pool state", "pool", a, "err", err)
+			continue
+		}
+		if !r.poolFactories[state.Factory] {
+			log.Debugw("skip pool of unknown factory", "pool", a, "factory", state.Factory)
+			continue
+		}
+		// the tokens and the factory are what the contract reports, the factory must have created it
+		verified, err := r.poolRPC.VerifyPool(ctx, a, state)
+		if err != nil {
+			log.Debugw("error when verify pool", "pool", a, "err", err)
+			continue
+		}
+		if !verified {
+			log.Debugw("skip pool unknown to its factory", "pool", a, "factory", state.Factory)
+			continue
+		}
+		known[a] = common.LiquidityPool{}
+		pools = append(pools, common.LiquidityPool{
+			ChainID:        chainID,
+			Address:        a,
+			Kind:           state.Kind,
+			Factory:        state.Factory,
+			Token0:         state.Token0,
+			Token1:         state.Token1,
+			Fee:            state.Fee,
+			FirstSeenBlock: last,
+		})
+	}
+	if err := r.poolRegistry.SavePools(ctx, pools); err != nil {
+		log.Errorw("error when save pools", "pools", len(pools), "err", err)
+		return
+	}
+	r.pendingPools = pending
+	log.Infow("finish register pools", "registered", len(pools), "pending", len(pending))
+}
+
+func (r *RateWorker) priceFromPools(ctx context.Context, log *zap.SugaredLogger) {
+	chainData := r.chainData[common.ChainBase]
+	tokens := []string{}
+	for a := range chainData.tokenPools {
+		if _, priced := chainData.dexTokens[a]; priced || !r.owns(a) {
+			continue
+		}
+		tokens = append(tokens, a)
+	}
+	anchors := make([]string, 0, len(r.anchors))
+	for a := range r.anchors {
+		anchors = append(anchors, a)
+	}
+
+	now := time.Now()
+	poolTokens := map[string]common.Token{}
+	reserves := []common.PoolReserve{}
+	reads := 0
+	for bg := 0; bg < len(tokens) && reads < poolPriceBatch; bg += poolTokensChunk {
+		end := bg + poolTokensChunk
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		pools, err := r.poolRegistry.GetPoolsOfTokens(ctx, common.ChainBase.String(), tokens[bg:end], anchors)
+		if err != nil {
+			log.Errorw("error when get pools of tokens", "err", err)
+			return
+		}
+		for _, pool := range pools {
+			if reads >= poolPriceBatch {
+				break
+			}
+			reads++
+			token, anchorAddress := pool.Token0, pool.Token1
+			if _, isAnchor := r.anchors[pool.Token0]; isAnchor {
+				token, anchorAddress = pool.Token1, pool.Token0
+			}
+			anchor := r.anchors[anchorAddress]
+			anchorUsd := chainData.anchorRates[anchorAddress]
+			if anchorUsd == 0 {
+				continue
+			}
+			state, err := r.poolRPC.GetPoolState(ctx, pool.Address)
+			if err != nil {
+				log.Debugw("error when get pool state", "pool", pool.Address, "err", err)
+				continue
+			}
+			decimals0, ok0 := r.tokenDecimals(ctx, pool.Token0)
+			decimals1, ok1 := r.tokenDecimals(ctx, pool.Token1)
+			if !ok0 || !ok1 {
+				continue
+			}
+			p0 := price0(state, decimals0, decimals1)
+			if p0 == 0 || math.IsInf(p0, 0) || math.IsNaN(p0) {
+				continue
+			}
+			reserve := common.PoolReserve{
+				PoolAddress: pool.Address,
+				BlockNumber: chainData.lastStoredBlock,
+				Price0:      p0,
+				ObservedAt:  now,
+			}
+			if state.Kind == common.PoolKindV3 {
+				reserve.SqrtPriceX96 = state.SqrtPriceX96.String()
+			}
+			// the liquidity is the balances of the pool, a token can report any reserves
+			balance0, err := r.poolRPC.GetBalance(ctx, pool.Token0, pool.Address)
+			if err != nil {
+				log.Debugw("error when get pool balance", "pool", pool.Address, "token", pool.Token0, "err", err)
+				continue
+			}
+			balance1, err := r.poolRPC.GetBalance(ctx, pool.Token1, pool.Address)
+			if err != nil {
+				log.Debugw("error when get pool balance", "pool", pool.Address, "token", pool.Token1, "err", err)
+				continue
+			}
+			reserve.Reserve0, reserve.Reserve1 = toUnits(balance0, decimals0), toUnits(balance1, decimals1)
+			reserves = append(reserves, reserve)
+
+			usdPrice, anchorReserve := p0*anchorUsd, reserve.Reserve1
+			if token == pool.Token1 {
+				usdPrice, anchorReserve = anchorUsd/p0, reserve.Reserve0
+			}
+			liquidity := 2 * anchorReserve * anchorUsd
+			if liquidity < minLiquidity {
+				continue
+			}
+			if current, exist := poolTokens[token]; exist && current.LiquidityUsd >= liquidity {
+				continue
+			}
+			t := common.Token{
+				UsdPrice:          usdPrice,
+				Address:           token,
+				ChainID:           common.ChainBase.String(),
+				SourcePrice:       common.SourcePricePool,
+				DexID:             pool.Kind.String(),
+				PairAddress:       pool.Address,
+				QuoteTokenAddress: anchor.Address,
+				QuoteTokenSymbol:  anchor.Symbol,
+				LiquidityUsd:      liquidity,
+				UpdatedAt:         now.Unix(),
+			}
+			if m, exist := r.metadata[token]; exist {
+				t.Symbol = m.Symbol
+			}
+			poolTokens[token] = t
+		}
+	}
+	if err := r.poolRegistry.InsertPoolReserves(ctx, reserves); err != nil {
+		log.Errorw("error when insert pool reserves", "reserves", len(reserves), "err", err)
+	}
+	r.poolTokens = poolTokens
+	log.Infow("finish price from pools", "tokens", len(tokens), "reads", reads, "priced", len(poolTokens))
+}
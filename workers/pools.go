@@ -0,0 +1,63 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"go.uber.org/zap"
+)
+
+const tokenPoolsKeyPrefix = "dex_screener_pools:"
+
+// the pools of a token which isn't refreshed anymore expire
+const tokenPoolsTTL = 7 * 24 * time.Hour
+
+// TokenPoolsKey is the key of the qualifying pools of a token, stored as common.RedisTokenPools.
+func TokenPoolsKey(address string) string {
+	return tokenPoolsKeyPrefix + strings.ToLower(address)
+}
+
+func poolOf(p common.Pair) common.Pool {
+	return common.Pool{
+		PairAddress:       p.PairAddress,
+		ChainID:           p.ChainID,
+		DexID:             p.DexID,
+		Url:               p.Url,
+		QuoteTokenAddress: p.QuoteToken.Address,
+		QuoteTokenSymbol:  p.QuoteToken.Symbol,
+		PriceUsd:          p.PriceUsd,
+		LiquidityUsd:      p.Liquidity.Usd,
+		VolumeH24:         p.Volume.H24,
+		TxnsH24:           p.Txns.H24,
+	}
+}
+
+// storePools stores the qualifying pools of every refreshed token, the pool with the most volume first.
+// A token without a qualifying pool is stored with an empty list.
+func (r *RateWorker) storePools(ctx context.Context, log *zap.SugaredLogger, pools map[string][]common.Pool, at time.Time) {
+	for address, p := range pools {
+		if ctx.Err() != nil {
+			return
+		}
+		sort.SliceStable(p, func(i, j int) bool {
+			return p[i].VolumeH24 > p[j].VolumeH24
+		})
+		data, err := json.Marshal(common.RedisTokenPools{
+			UpdatedTime:  at.Unix(),
+			TokenAddress: address,
+			Pools:        p,
+		})
+		if err != nil {
+			log.Errorw("error when marshal data", "err", err)
+			continue
+		}
+		key := TokenPoolsKey(address)
+		if err := r.inMemDB.Set(key, data, tokenPoolsTTL); err != nil {
+			log.Errorw("error when set key", "key", key, "err", err)
+		}
+	}
+}
@@ -1,26 +1,33 @@
 package workers
 
 import (
+	"context"
 	"encoding/json"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	obc "github.com/kv-base-hack/base-binance-client"
 	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/erc20"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
 	"github.com/kv-base-hack/base-token-rate/lib/rateprovider"
+	"github.com/kv-base-hack/base-token-rate/lib/tracing"
 	"github.com/kv-base-hack/base-token-rate/storage/db"
-	inmem "github.com/kv-base-hack/common/inmem_db"
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
 	"github.com/kv-base-hack/common/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-const ratePricesKey = "dex_screener_prices"
+// RatePricesKey is the key of the published rate snapshot, a json list of common.Token.
+const RatePricesKey = "dex_screener_prices"
 const delayTime = time.Second / 3
 const maxTokenPool = 30
 const maxTokenNumber = 6
 const maxBlockRange = 7200 * 30
+const seenTokensPageSize = 5000
 const eth = "ethereum"
 const sol = "solana"
 const minTotalTradeIn24h = 100
@@ -31,6 +38,11 @@ const chunkPairWithUsdt = 80
 type ChainData struct {
 	lastStoredBlock int64
 	tokenPools      map[string]int
+	dexTokens       map[string]common.Token
+	// hashes of the blocks with logs in the confirmation window
+	blockHashes map[int64]string
+	// usd rate of the anchors by address, set by the leader from the cex rates
+	anchorRates map[string]float64
 }
 
 type TokenPool struct {
@@ -41,36 +53,103 @@ type TokenPool struct {
 type RateWorker struct {
 	log                  *zap.SugaredLogger
 	duration             time.Duration
+	refreshTick          time.Duration
 	rateProvider         rateprovider.RateProvider
-	inMemDB              inmem.Inmem
+	inMemDB              kv.Store
 	db                   db.DB
 	kaivestBinanceClient *obc.KaivestBinanceClient
+	scheduler            *RefreshScheduler
+	leadership           Leadership
+	sharding             Sharding
+	status               StatusReporter
+	observers            []SnapshotObserver
+	depeg                *DepegMonitor
+	auditLog             db.AuditLog
+	identity             *IdentityResolver
+	supplyStore          db.SupplyStore
+	lockerAddresses      []string
+	metadataStore        db.MetadataStore
+	rpc                  *erc20.Client
+	discoveryFeed        db.DiscoveryFeed
+	discoveryPublisher   DiscoveryPublisher
+	riskStore            db.RiskStore
+	riskMaxLabel         common.RiskLabel
+	poolRegistry         db.PoolRegistry
+	poolRPC              *erc20.Client
+	anchors              map[string]common.Anchor
+	poolFactories        map[string]bool
+	reorgStore           db.ReorgStore
+	reorgWindow          int64
+	candleAnchors        []common.Anchor
 	chainData            map[common.Chain]*ChainData
+
+	lastFullCycle time.Time
+	cexTokens     []common.Token
+	audits        map[string]common.PriceAudit
+	lastAudited   map[string]float64
+	supply        map[string]common.TokenSupply
+	metadata      map[string]common.TokenMetadata
+	risk          map[string]common.TokenRisk
+	peakLiquidity map[string]float64
+	// the new tokens aren't assessed again until riskRetryInterval after a failed assessment
+	riskFailedAt time.Time
+	// pair addresses of the qualifying pools of a token
+	pairAddresses map[string][]string
+	// discovered tokens waiting for their first price
+	unpriced map[string]bool
+	// tokens priced from the pool registry, published if they have no dex price
+	poolTokens        map[string]common.Token
+	poolsScannedBlock int64
+	pendingPools      []string
+	decimals          map[string]int
+	// when the tokens registered without decimals were last retried
+	registryRetried map[string]time.Time
 }
 
-func NewRateWorker(log *zap.SugaredLogger, duration time.Duration,
-	rateProvider rateprovider.RateProvider, inMemDB inmem.Inmem, db db.DB, kaivestBinanceClient *obc.KaivestBinanceClient) *RateWorker {
+// NewRateWorker creates a rate worker. Cex rates and new tokens are refreshed every duration,
+// dex rates are refreshed every refreshTick for the tokens the scheduler reports as due.
+func NewRateWorker(log *zap.SugaredLogger, duration time.Duration, refreshTick time.Duration,
+	rateProvider rateprovider.RateProvider, inMemDB kv.Store, db db.DB, kaivestBinanceClient *obc.KaivestBinanceClient,
+	scheduler *RefreshScheduler, leadership Leadership) *RateWorker {
 	return &RateWorker{
 		log:                  log,
 		duration:             duration,
+		refreshTick:          refreshTick,
 		rateProvider:         rateProvider,
 		inMemDB:              inMemDB,
 		db:                   db,
 		kaivestBinanceClient: kaivestBinanceClient,
+		scheduler:            scheduler,
+		leadership:           leadership,
+		status:               nopStatusReporter{},
+		depeg:                NewDepegMonitor(common.BaseStablecoins, rateProvider, inMemDB),
+		audits:               make(map[string]common.PriceAudit),
+		lastAudited:          make(map[string]float64),
+		metadata:             make(map[string]common.TokenMetadata),
+		unpriced:             make(map[string]bool),
+		peakLiquidity:        make(map[string]float64),
+		pairAddresses:        make(map[string][]string),
+		decimals:             make(map[string]int),
+		registryRetried:      make(map[string]time.Time),
 
 		chainData: map[common.Chain]*ChainData{
 			common.ChainBase: {
 				lastStoredBlock: 0,
 				tokenPools:      make(map[string]int),
+				dexTokens:       make(map[string]common.Token),
+				blockHashes:     make(map[int64]string),
+				anchorRates:     make(map[string]float64),
 			},
 		},
 	}
 }
 
-func (r *RateWorker) getCexMap(log *zap.SugaredLogger) map[string]float64 {
+func (r *RateWorker) getCexMap(ctx context.Context, log *zap.SugaredLogger) map[string]float64 {
 	ratesMap := map[string]float64{}
 	// get rate from cex first
+	start := time.Now()
 	pairWithUdst, err := r.kaivestBinanceClient.GetPairsWithUsdt()
+	metrics.ObserveProviderCall(metrics.ProviderKaivestBinance, err, start)
 	if err != nil {
 		log.Errorw("error when get pair with usdt", "err", err)
 		return map[string]float64{}
@@ -83,8 +162,14 @@ func (r *RateWorker) getCexMap(log *zap.SugaredLogger) map[string]float64 {
 			end = len(pairWithUdst)
 		}
 		symbols := strings.Join(pairWithUdst[bg:end], ",")
+		_, span := tracing.Tracer().Start(ctx, "kaivest_binance.book_ticker",
+			trace.WithAttributes(attribute.Int("symbols", end-bg)))
+		start := time.Now()
 		rates, err := r.kaivestBinanceClient.GetSpotBookTicker(symbols)
+		metrics.ObserveProviderCall(metrics.ProviderKaivestBinance, err, start)
+		tracing.EndSpan(span, err)
 		if err != nil {
+			metrics.BatchesDropped.WithLabelValues(metrics.ProviderKaivestBinance).Inc()
 			log.Errorw("error when get book ticker for pairs", "symbols", symbols, "err", err)
 			continue
 		}
@@ -111,35 +196,52 @@ func (r *RateWorker) validNetwork(nw string) bool {
 	return false
 }
 
-func (r *RateWorker) getNewAddresses(log *zap.SugaredLogger, chain common.Chain, lastStored int64, lastStoredBlockDb int64) []string {
+// getNewAddresses upserts the tokens of the block range into the seen tokens and returns the tokens last seen
+// in the range, read in pages so a large range doesn't load in one query.
+func (r *RateWorker) getNewAddresses(ctx context.Context, log *zap.SugaredLogger, chain common.Chain, lastStored int64, lastStoredBlockDb int64) []string {
 	var tradeTable string
 	var transferTable string
 	if chain == common.ChainBase {
 		tradeTable = db.BaseTradeLogs
 		transferTable = db.BaseTransferLogs
 	}
-	newAddressTrades, err := r.db.GetUniqueTokenAddressByRangeForTrade(tradeTable, lastStored+1, lastStoredBlockDb)
+	updated, err := r.db.UpdateSeenTokens(ctx, chain.String(), tradeTable, transferTable, lastStored+1, lastStoredBlockDb)
 	if err != nil {
-		log.Errorw("error when new token address by range",
+		log.Errorw("error when update seen tokens",
 			"lastStored", lastStored, "lastStoredBlockDb", lastStoredBlockDb, "err", err)
 		return []string{}
 	}
 
-	newAddressTransfer, err := r.db.GetUniqueTokenAddressByRangeForTransfer(transferTable, lastStored+1, lastStoredBlockDb)
-	if err != nil {
-		log.Errorw("error when new token address by range",
-			"lastStored", lastStored, "lastStoredBlockDb", lastStoredBlockDb, "err", err)
-		return []string{}
+	newAddress := []string{}
+	var cursor common.SeenTokenCursor
+	for {
+		page, err := r.db.GetSeenTokensByRange(ctx, chain.String(), lastStored+1, lastStoredBlockDb, cursor, seenTokensPageSize)
+		if err != nil {
+			log.Errorw("error when get seen tokens by range",
+				"lastStored", lastStored, "lastStoredBlockDb", lastStoredBlockDb, "cursor", cursor, "err", err)
+			return []string{}
+		}
+		for _, t := range page {
+			newAddress = append(newAddress, t.Address)
+		}
+		if len(page) < seenTokensPageSize {
+			break
+		}
+		last := page[len(page)-1]
+		cursor = common.SeenTokenCursor{LastSeenBlock: last.LastSeenBlock, Address: last.Address}
 	}
-	newAddress := append(newAddressTrades, newAddressTransfer...)
+	log.Debugw("seen tokens", "updated", updated, "tokens", len(newAddress))
 	return newAddress
 }
 
-func (r *RateWorker) updateTokenPoolFromBase(log *zap.SugaredLogger, existedOnCex map[string]bool) {
-	lastEthStoredBlockDb, err := r.db.GetLastStoredBlock(db.BaseTradeLogs)
+func (r *RateWorker) updateTokenPoolFromBase(ctx context.Context, log *zap.SugaredLogger, existedOnCex map[string]bool) error {
+	lastEthStoredBlockDb, err := r.db.GetLastStoredBlock(ctx, db.BaseTradeLogs)
 	if err != nil {
 		log.Errorw("error when get last ethereum stored block in db", "err", err)
-		return
+		return err
+	}
+	if reorg := r.checkReorg(ctx, log, lastEthStoredBlockDb); reorg > 0 {
+		r.rewind(ctx, log, reorg, lastEthStoredBlockDb)
 	}
 	lastStored := r.chainData[common.ChainBase].lastStoredBlock
 	if lastStored < lastEthStoredBlockDb-maxBlockRange {
@@ -147,10 +249,15 @@ func (r *RateWorker) updateTokenPoolFromBase(log *zap.SugaredLogger, existedOnCe
 	}
 
 	log.Infow("set rate", "lastEthStoredBlockDb", lastEthStoredBlockDb, "lastStored", lastStored)
+	blockLag := lastEthStoredBlockDb - r.chainData[common.ChainBase].lastStoredBlock
+	metrics.BlockLag.WithLabelValues(common.ChainBase.String()).Set(float64(blockLag))
+	r.status.SetBlockLag(common.ChainBase, blockLag)
 
 	// update new address for ethereum
-	newAddress := r.getNewAddresses(log, common.ChainBase, lastStored+1, lastEthStoredBlockDb)
+	newAddress := r.getNewAddresses(ctx, log, common.ChainBase, lastStored, lastEthStoredBlockDb)
+	discovered := []string{}
 	for _, a := range newAddress {
+		a = strings.ToLower(a)
 		// get from cex, dont need to get from dex
 		if _, exist := existedOnCex[a]; exist {
 			continue
@@ -158,21 +265,46 @@ func (r *RateWorker) updateTokenPoolFromBase(log *zap.SugaredLogger, existedOnCe
 		if _, exist := r.chainData[common.ChainBase].tokenPools[a]; !exist {
 			// new pool for token
 			r.chainData[common.ChainBase].tokenPools[a] = 0
+			r.scheduler.Track([]string{a})
+			discovered = append(discovered, a)
 		}
 	}
+	// the first scan after a start without state sees the old tokens, they aren't new
+	if r.chainData[common.ChainBase].lastStoredBlock > 0 {
+		r.recordDiscoveries(ctx, log, discovered, lastEthStoredBlockDb)
+	}
 	r.chainData[common.ChainBase].lastStoredBlock = lastEthStoredBlockDb
+	metrics.TokenPools.WithLabelValues(common.ChainBase.String()).Set(float64(len(r.chainData[common.ChainBase].tokenPools)))
+	return nil
 }
 
-func (r *RateWorker) setRateToStorage() {
-	log := r.log.With("ID", utils.RandomString(21))
-	ratesMap := r.getCexMap(log)
+// updateActivity sets the refresh tier of every token from its trades in the last activityBlockRange blocks.
+func (r *RateWorker) updateActivity(ctx context.Context, log *zap.SugaredLogger) {
+	last := r.chainData[common.ChainBase].lastStoredBlock
+	trades, err := r.db.GetTradeCountByRange(ctx, db.BaseTradeLogs, last-activityBlockRange, last)
+	if err != nil {
+		log.Errorw("error when get trade count by range", "last", last, "err", err)
+		return
+	}
+	lowerTrades := make(map[string]int64, len(trades))
+	for a, n := range trades {
+		lowerTrades[strings.ToLower(a)] += n
+	}
+	r.scheduler.UpdateActivity(lowerTrades)
+}
+
+func (r *RateWorker) getCexTokens(ctx context.Context, log *zap.SugaredLogger) ([]common.Token, map[string]bool, map[string]float64) {
+	ratesMap := r.getCexMap(ctx, log)
 
+	start := time.Now()
 	coins, err := r.kaivestBinanceClient.GetAllCoinInfo()
+	metrics.ObserveProviderCall(metrics.ProviderKaivestBinance, err, start)
 	if err != nil {
 		log.Errorw("error when get all coins", "err", err)
 	}
 
 	tokens := []common.Token{}
+	now := time.Now()
 
 	existedOnCex := map[string]bool{}
 
@@ -198,125 +330,386 @@ func (r *RateWorker) setRateToStorage() {
 				Symbol:      n.Coin,
 				ChainID:     chainID,
 				SourcePrice: common.SourcePriceCex,
+				UpdatedAt:   now.Unix(),
 			})
 			existedOnCex[strings.ToLower(n.ContractAddress)] = true
 		}
 	}
 	log.Infow("finish get rate from cex", "tokens", tokens)
-	r.updateTokenPoolFromBase(log, existedOnCex)
+	return tokens, existedOnCex, ratesMap
+}
+
+func (r *RateWorker) getPairs(ctx context.Context, log *zap.SugaredLogger, addresses []string) ([]common.Pair, error) {
+	tokens := strings.Join(addresses, ",")
+	log.Infow("get rates for", "tokens", tokens)
+	_, span := tracing.Tracer().Start(ctx, "dexscreener.batch", trace.WithAttributes(attribute.Int("tokens", len(addresses))))
+	rates, err := r.rateProvider.GetPrices(tokens)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		metrics.BatchesDropped.WithLabelValues(metrics.ProviderDexScreener).Inc()
+		log.Errorw("error when get rates", "err", err)
+		return nil, err
+	}
+	return rates.Pairs, nil
+}
+
+// refreshDexTokens gets dex rates for due tokens, using at most maxRequests provider requests.
+func (r *RateWorker) refreshDexTokens(ctx context.Context, log *zap.SugaredLogger, due []string, maxRequests int) error {
+	chainData := r.chainData[common.ChainBase]
 	tokenPool := []TokenPool{}
-	for _, v := range r.chainData {
-		for a, p := range v.tokenPools {
-			tokenPool = append(tokenPool, TokenPool{
-				Address:      a,
-				NumberOfPool: p,
-			})
+	for _, a := range due {
+		if !r.owns(a) {
+			continue
 		}
+		tokenPool = append(tokenPool, TokenPool{
+			Address:      a,
+			NumberOfPool: chainData.tokenPools[a],
+		})
 	}
 
-	sort.Slice(tokenPool, func(i, j int) bool {
-		return tokenPool[i].NumberOfPool < tokenPool[j].NumberOfPool
-	})
-
 	allPairs := []common.Pair{}
+	requested := []string{}
+	requests := 0
 	totalPool := 0
-	totalToken := 0
 	addresses := []string{}
 	for _, t := range tokenPool {
-		if totalToken+1 > maxTokenNumber || totalPool+t.NumberOfPool > maxTokenPool {
-			// call request
-			tokens := strings.Join(addresses, ",")
-			log.Infow("get rates for", "tokens", tokens)
-			rates, err := r.rateProvider.GetPrices(tokens)
-			if err != nil {
-				log.Errorw("error when get rates", "err", err)
-				continue
+		if len(addresses) > 0 && (len(addresses)+1 > maxTokenNumber || totalPool+t.NumberOfPool > maxTokenPool) {
+			if requests >= maxRequests {
+				break
+			}
+			requests++
+			pairs, err := r.getPairs(ctx, log, addresses)
+			if err == nil {
+				allPairs = append(allPairs, pairs...)
+				requested = append(requested, addresses...)
 			}
-			allPairs = append(allPairs, rates.Pairs...)
 			time.Sleep(delayTime)
 
-			totalToken = 1
-			totalPool = t.NumberOfPool
-			addresses = []string{t.Address}
-			continue
+			totalPool = 0
+			addresses = []string{}
 		}
-		totalToken += 1
 		totalPool += t.NumberOfPool
 		addresses = append(addresses, t.Address)
 	}
-	if totalToken > 0 {
-		tokens := strings.Join(addresses, ",")
-		log.Infow("get rates for", "tokens", tokens)
-		rates, err := r.rateProvider.GetPrices(tokens)
-		if err != nil {
-			log.Errorw("error when get rates", "err", err)
-		} else {
-			allPairs = append(allPairs, rates.Pairs...)
+	if len(addresses) > 0 && requests < maxRequests {
+		requests++
+		pairs, err := r.getPairs(ctx, log, addresses)
+		if err == nil {
+			allPairs = append(allPairs, pairs...)
+			requested = append(requested, addresses...)
 		}
 	}
 	log.Infow("allPairs", "allPairs", allPairs)
 	poolOfToken := map[string]int{}
-	maxVolume := map[string]float64{}
+	maxLiquidity := map[string]float64{}
+	chosen := map[string]common.Pair{}
+	pools := map[string][]common.Pool{}
+	rejected := map[string]common.RejectedQuotes{}
 
 	for _, p := range allPairs {
-		if p.ChainID != eth && p.ChainID != sol {
+		address := strings.ToLower(p.BaseToken.Address)
+		// the addresses are read from the base logs, a pair of another chain quotes another contract,
+		// and the pool priced tokens are published under the same chain id
+		if p.ChainID != common.ChainBase.String() {
+			metrics.TokensFiltered.WithLabelValues("chain").Inc()
+			rejected[address] = append(rejected[address], rejectedQuote(p, "chain"))
 			continue
 		}
+		if p.Liquidity.Usd > maxLiquidity[address] {
+			maxLiquidity[address] = p.Liquidity.Usd
+		}
 		// shouldn't get rate from stale pool
-		if p.Txns.H24.Buys+p.Txns.H24.Sells <= minTotalTradeIn24h ||
-			p.Txns.H24.Buys <= minTotalBuyIn24h || p.Liquidity.Usd < minLiquidity {
+		if threshold := staleThreshold(p); threshold != "" {
+			metrics.TokensFiltered.WithLabelValues(threshold).Inc()
+			rejected[address] = append(rejected[address], rejectedQuote(p, threshold))
 			continue
 		}
 
-		poolOfToken[p.BaseToken.Address]++
-		if currentVolume, exist := maxVolume[p.BaseToken.Address]; exist {
+		poolOfToken[address]++
+		pools[address] = append(pools[address], poolOf(p))
+		if current, exist := chosen[address]; exist {
 			// choose the pool has max volume
-			if currentVolume > p.Volume.H24 {
+			if current.Volume.H24 > p.Volume.H24 {
+				rejected[address] = append(rejected[address], rejectedQuote(p, "lower_volume"))
 				continue
 			}
+			rejected[address] = append(rejected[address], rejectedQuote(current, "lower_volume"))
 		}
-		maxVolume[p.BaseToken.Address] = p.Volume.H24
-		tokens = append(tokens, common.Token{
+		chosen[address] = p
+	}
+
+	now := time.Now()
+	for address, p := range chosen {
+		chainData.dexTokens[address] = common.Token{
 			UsdPrice:    p.PriceUsd,
 			Address:     p.BaseToken.Address,
 			Symbol:      p.BaseToken.Symbol,
 			ChainID:     p.ChainID,
 			SourcePrice: common.SourcePriceDex,
 			ImageUrl:    p.Info.ImageUrl,
+			DexID:       p.DexID,
+			Url:         p.Url,
+			UpdatedAt:   now.Unix(),
+
+			QuoteTokenAddress: p.QuoteToken.Address,
+
+			PairAddress:      p.PairAddress,
+			QuoteTokenSymbol: p.QuoteToken.Symbol,
+			LiquidityUsd:     p.Liquidity.Usd,
+			VolumeH24:        p.Volume.H24,
+			TxnsH24:          &common.Txns{Buys: p.Txns.H24.Buys, Sells: p.Txns.H24.Sells},
+			Fdv:              p.Fdv,
 
 			PriceChangeM5:  p.PriceChange.M5,
 			PriceChangeH1:  p.PriceChange.H1,
 			PriceChangeH6:  p.PriceChange.H6,
 			PriceChangeH24: p.PriceChange.H24,
+		}
+		r.recordAudit(common.PriceAudit{
+			TokenAddress: address,
+			ChainID:      p.ChainID,
+			Symbol:       p.BaseToken.Symbol,
+			UsdPrice:     p.PriceUsd,
+			SourcePrice:  common.SourcePriceDex,
+			DexID:        p.DexID,
+			Url:          p.Url,
+			PairAddress:  p.PairAddress,
+			LiquidityUsd: p.Liquidity.Usd,
+			VolumeH24:    p.Volume.H24,
+			Rejected:     rejected[address],
+			PublishedAt:  now,
 		})
+		r.recordFirstPrice(ctx, log, address, p, now)
 	}
 
+	// a token whose pairs stop qualifying must not keep its last price
+	for _, addr := range requested {
+		addr = strings.ToLower(addr)
+		if _, exist := chosen[addr]; !exist {
+			delete(chainData.dexTokens, addr)
+			delete(r.pairAddresses, addr)
+			// the stored pools are overwritten, they would be served until they expire
+			pools[addr] = []common.Pool{}
+		}
+	}
 	for addr, value := range poolOfToken {
-		r.chainData[common.ChainBase].tokenPools[addr] = value
+		chainData.tokenPools[addr] = value
+	}
+	r.storePools(ctx, log, pools, now)
+	for addr, value := range maxLiquidity {
+		r.scheduler.ObserveLiquidity(addr, value)
+		r.observePeakLiquidity(addr, value)
+	}
+	for addr, p := range pools {
+		pairs := make([]string, 0, len(p))
+		for _, pool := range p {
+			pairs = append(pairs, pool.PairAddress)
+		}
+		r.pairAddresses[addr] = pairs
+	}
+	r.scheduler.MarkRefreshed(requested, now)
+	log.Infow("refreshed dex tokens", "due", len(due), "requested", len(requested), "requests", requests)
+	if requests > 0 && len(requested) == 0 {
+		return errAllBatchesFailed
+	}
+	return nil
+}
+
+// staleThreshold returns the publishing threshold the pair fails, or an empty string if its rate can be used.
+// The sells count toward min_trades_24h, they were always 0 while the pair txns were decoded from "sell".
+func staleThreshold(p common.Pair) string {
+	switch {
+	case p.Txns.H24.Buys+p.Txns.H24.Sells <= minTotalTradeIn24h:
+		return "min_trades_24h"
+	case p.Txns.H24.Buys <= minTotalBuyIn24h:
+		return "min_buys_24h"
+	case p.Liquidity.Usd < minLiquidity:
+		return "min_liquidity"
+	default:
+		return ""
+	}
+}
+
+// SetIdentityResolver makes the worker publish the cross references of the tokens.
+func (r *RateWorker) SetIdentityResolver(identity *IdentityResolver) {
+	r.identity = identity
+}
+
+// ownedTokens returns the base tokens the replica publishes, the dex tokens and the tokens priced from their pools.
+func (r *RateWorker) ownedTokens() map[string]common.Token {
+	dexTokens := r.chainData[common.ChainBase].dexTokens
+	tokens := make(map[string]common.Token, len(dexTokens)+len(r.poolTokens))
+	for a, t := range r.poolTokens {
+		if r.owns(a) {
+			tokens[a] = t
+		}
+	}
+	for a, t := range dexTokens {
+		if r.owns(a) {
+			tokens[a] = t
+		}
+	}
+	return tokens
+}
+
+func (r *RateWorker) publish(ctx context.Context, log *zap.SugaredLogger) error {
+	tokens := append([]common.Token{}, r.cexTokens...)
+	for _, v := range r.chainData {
+		for a, t := range v.dexTokens {
+			if !r.owns(a) {
+				continue
+			}
+			t, publish := r.applyRisk(r.applyMetadata(r.applySupply(r.depeg.flagQuoteDepeg(t))))
+			if !publish {
+				continue
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	for a, t := range r.poolTokens {
+		if _, priced := r.chainData[common.ChainBase].dexTokens[a]; priced || !r.owns(a) {
+			continue
+		}
+		t, publish := r.applyRisk(r.applyMetadata(r.applySupply(r.depeg.flagQuoteDepeg(t))))
+		if !publish {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	if r.identity != nil {
+		for i := range tokens {
+			tokens[i] = r.identity.Resolve(ctx, tokens[i])
+		}
 	}
 
 	log.Infow("tokens", "tokens", tokens)
+	if r.sharding == nil {
+		observeTokensPublished(tokens)
+	}
 
 	data, err := json.Marshal(tokens)
 	if err != nil {
-		r.log.Errorw("error when marshal data", "err", err)
-		return
+		log.Errorw("error when marshal data", "err", err)
+		return err
 	}
 
-	// no expire
-	err = r.inMemDB.Set(ratePricesKey, data, 0)
+	key, expiration := RatePricesKey, time.Duration(0)
+	if r.sharding != nil {
+		key, expiration = shardPricesKey(r.sharding.ID()), shardSnapshotTTL
+	}
+	// the set takes no context, don't publish after the leadership was lost
+	if err := ctx.Err(); err != nil {
+		log.Warnw("cycle cancelled before publish", "err", err)
+		return err
+	}
+	_, span := tracing.Tracer().Start(ctx, "redis.set", trace.WithAttributes(attribute.String("key", key)))
+	err = r.inMemDB.Set(key, data, expiration)
+	tracing.EndSpan(span, err)
 	if err != nil {
-		r.log.Errorw("error when set key", "key", ratePricesKey, "err", err)
+		log.Errorw("error when set key", "key", key, "err", err)
+		return err
+	}
+	if r.sharding == nil {
+		notifyObservers(ctx, r.observers, tokens)
+	}
+	r.writeAudits(ctx, log, tokens)
+	log.Infow("finish set rates")
+	return nil
+}
+
+func observeTokensPublished(tokens []common.Token) {
+	published := map[common.SourcePrice]int{}
+	for _, t := range tokens {
+		published[t.SourcePrice]++
+	}
+	for _, source := range common.SourcePriceValues() {
+		metrics.TokensPublished.WithLabelValues(source.String()).Set(float64(published[source]))
+	}
+}
+
+// setRateToStorage runs a cycle and returns the first error which made the published rates stale.
+func (r *RateWorker) setRateToStorage() error {
+	id := utils.RandomString(21)
+	ctx, span := tracing.Tracer().Start(r.cycleContext(), "rate_worker.cycle",
+		trace.WithAttributes(attribute.String("cycle.id", id)))
+	log := r.log.With("ID", id, "trace_id", span.SpanContext().TraceID().String())
+	now := time.Now()
+	defer func() {
+		span.End()
+		metrics.CycleDuration.WithLabelValues(RateWorkerName).Observe(time.Since(now).Seconds())
+	}()
+	var cycleErr error
+	// the full cycle requests to the rate provider come out of the tick budget
+	maxRequests := r.scheduler.MaxRequests(r.refreshTick)
+	if now.Sub(r.lastFullCycle) >= r.duration {
+		// with sharding only the leader prices the cex tokens and discovers the tokens,
+		// the other replicas follow its state and refresh their shard
+		leader := r.leadership.IsLeader()
+		if leader {
+			var existedOnCex map[string]bool
+			var cexRates map[string]float64
+			r.cexTokens, existedOnCex, cexRates = r.getCexTokens(ctx, log)
+			maxRequests -= r.depeg.Update(ctx, log, cexRates)
+			r.recordCexAudits(now)
+			r.updateAnchorRates(cexRates)
+			cycleErr = r.updateTokenPoolFromBase(ctx, log, existedOnCex)
+		} else {
+			r.cexTokens = nil
+			r.restoreDiscovery(ctx, log)
+			r.depeg.Load(ctx, log)
+		}
+		r.loadUnpriced(ctx, log)
+		r.updateActivity(ctx, log)
+		r.updateSupply(ctx, log)
+		r.updateRegistry(ctx, log)
+		r.updateRisk(ctx, log)
+		r.updatePools(ctx, log, leader)
+		r.checkpoint(ctx, log, leader)
+		r.lastFullCycle = now
 	}
-	r.log.Infow("finish set rates")
+	if err := r.refreshDexTokens(ctx, log, r.scheduler.Due(now), maxRequests); err != nil && cycleErr == nil {
+		cycleErr = err
+	}
+	r.assessNewTokens(ctx, log)
+	if err := r.publish(ctx, log); err != nil {
+		return err
+	}
+	return cycleErr
+}
+
+// cycleContext is cancelled when the leadership is lost, a shard replica refreshes its shard either way.
+func (r *RateWorker) cycleContext() context.Context {
+	if r.sharding != nil {
+		return context.Background()
+	}
+	return r.leadership.Context()
 }
 
 func (r *RateWorker) Run() error {
-	log := r.log.With("worker", "rate_worker")
+	log := r.log.With("worker", RateWorkerName)
 	log.Infow("start run rate worker")
-	for {
-		r.setRateToStorage()
-		time.Sleep(r.duration)
+	wasLeader, started := false, false
+	ticker := time.NewTicker(r.refreshTick)
+	for ; ; <-ticker.C {
+		leader := r.leadership.IsLeader()
+		if !leader && r.sharding == nil {
+			if wasLeader {
+				log.Infow("lost leadership, standing by")
+			}
+			wasLeader = false
+			continue
+		}
+		if (leader && !wasLeader) || !started {
+			// the previous leader may have moved on since this replica last ran
+			r.restore(r.cycleContext(), log)
+			r.lastFullCycle = time.Time{}
+			started = true
+		}
+		wasLeader = leader
+		if err := r.setRateToStorage(); err != nil {
+			r.status.CycleFailed(RateWorkerName, err)
+			continue
+		}
+		r.status.CycleSucceeded(RateWorkerName)
 	}
-	return nil
 }
@@ -0,0 +1,136 @@
+package workers
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/erc20"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+// max number of tokens added to the metadata registry per cycle
+const registryBatch = 200
+const registryLoadChunk = 1000
+
+// a token registered without decimals, while the rpc was failing, is retried every registryRetryInterval
+const registryRetryInterval = time.Hour
+
+// SetMetadataRegistry makes the worker register the metadata of the tracked tokens and publish the
+// registered name, symbol and decimals. Without a rpc client only the first seen block and first minter
+// from the transfer logs are registered.
+func (r *RateWorker) SetMetadataRegistry(store db.MetadataStore, rpc *erc20.Client) {
+	r.metadataStore = store
+	r.rpc = rpc
+}
+
+// updateRegistry loads the registered metadata of the tracked tokens and registers the unknown ones.
+func (r *RateWorker) updateRegistry(ctx context.Context, log *zap.SugaredLogger) {
+	if r.metadataStore == nil {
+		return
+	}
+	chainID := common.ChainBase.String()
+	unknown := []string{}
+	for a := range r.chainData[common.ChainBase].tokenPools {
+		if _, exist := r.metadata[a]; !exist && r.owns(a) {
+			unknown = append(unknown, a)
+		}
+	}
+	for bg := 0; bg < len(unknown); bg += registryLoadChunk {
+		end := bg + registryLoadChunk
+		if end > len(unknown) {
+			end = len(unknown)
+		}
+		registered, err := r.metadataStore.GetTokenMetadata(ctx, chainID, unknown[bg:end])
+		if err != nil {
+			log.Errorw("error when get token metadata", "err", err)
+			return
+		}
+		for a, m := range registered {
+			r.metadata[a] = m
+		}
+	}
+
+	missing := []string{}
+	for _, a := range unknown {
+		if _, exist := r.metadata[a]; !exist {
+			missing = append(missing, a)
+		}
+		if len(missing) == registryBatch {
+			break
+		}
+	}
+	if r.rpc != nil {
+		now := time.Now()
+		for a, m := range r.metadata {
+			if len(missing) >= registryBatch {
+				break
+			}
+			if m.Decimals == nil && r.owns(a) && now.Sub(r.registryRetried[a]) >= registryRetryInterval {
+				r.registryRetried[a] = now
+				missing = append(missing, a)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	firstSeen, err := r.metadataStore.GetTokenFirstSeen(ctx, db.BaseTransferLogs, missing)
+	if err != nil {
+		log.Errorw("error when get token first seen", "err", err)
+		return
+	}
+	metadata := make([]common.TokenMetadata, 0, len(missing))
+	for _, a := range missing {
+		m := common.TokenMetadata{ChainID: chainID, Address: a}
+		if f, exist := firstSeen[a]; exist {
+			block := f.Block
+			m.FirstSeenBlock, m.FirstMinter = &block, f.FirstMinter
+		}
+		if r.rpc != nil {
+			onChain, err := r.rpc.GetMetadata(ctx, a)
+			if err != nil {
+				// registered with the next cycle
+				log.Errorw("error when get erc20 metadata", "address", a, "err", err)
+				continue
+			}
+			m.Name, m.Symbol, m.Decimals = onChain.Name, onChain.Symbol, onChain.Decimals
+			if onChain.TotalSupply != nil && onChain.Decimals != nil {
+				supply := new(big.Float).SetInt(onChain.TotalSupply)
+				unit := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(*onChain.Decimals)), nil))
+				totalSupply, _ := supply.Quo(supply, unit).Float64()
+				m.TotalSupply = &totalSupply
+			}
+		}
+		metadata = append(metadata, m)
+	}
+	if err := r.metadataStore.SaveTokenMetadata(ctx, metadata); err != nil {
+		log.Errorw("error when save token metadata", "tokens", len(metadata), "err", err)
+		return
+	}
+	for _, m := range metadata {
+		r.metadata[m.Address] = m
+	}
+	log.Infow("finish update metadata registry", "registered", len(metadata), "missing", len(missing))
+}
+
+// applyMetadata sets the registered name, symbol and decimals of a dex token, the registered total
+// supply prices the fdv when the transfer logs gave no supply.
+func (r *RateWorker) applyMetadata(t common.Token) common.Token {
+	m, exist := r.metadata[strings.ToLower(t.Address)]
+	if !exist {
+		return t
+	}
+	if m.Symbol != "" {
+		t.Symbol = m.Symbol
+	}
+	t.Name, t.Decimals = m.Name, m.Decimals
+	if t.TotalSupply == 0 && m.TotalSupply != nil && *m.TotalSupply > 0 {
+		t.TotalSupply = *m.TotalSupply
+		t.Fdv = t.UsdPrice * t.TotalSupply
+	}
+	return t
+}
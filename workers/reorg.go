@@ -0,0 +1,150 @@
+package workers
+
+import (
+	"context"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+// SetReorgStore makes the worker track the hashes of the last window blocks and rewind the
+// discovery, the metadata and the pools derived from the blocks the indexer rewrote after a reorg.
+// The backfilled candles of those blocks are rebuilt against the anchors.
+func (r *RateWorker) SetReorgStore(store db.ReorgStore, window int64, anchors []common.Anchor) {
+	r.reorgStore = store
+	r.reorgWindow = window
+	r.candleAnchors = anchors
+}
+
+// checkReorg compares the tracked hashes with the logs and returns the first rewritten block, 0 if none.
+// The hashes of the window up to last are tracked for the next check.
+func (r *RateWorker) checkReorg(ctx context.Context, log *zap.SugaredLogger, last int64) int64 {
+	if r.reorgStore == nil || r.reorgWindow <= 0 {
+		return 0
+	}
+	chainData := r.chainData[common.ChainBase]
+	windowStart := last - r.reorgWindow + 1
+	from := windowStart
+	for b := range chainData.blockHashes {
+		if b < from {
+			from = b
+		}
+	}
+	hashes := map[int64]string{}
+	for _, table := range []string{db.BaseTradeLogs, db.BaseTransferLogs} {
+		tableHashes, err := r.reorgStore.GetBlockHashes(ctx, table, from, last)
+		if err != nil {
+			log.Errorw("error when get block hashes", "table", table, "from", from, "to", last, "err", err)
+			return 0
+		}
+		for b, h := range tableHashes {
+			hashes[b] = h
+		}
+	}
+
+	var reorg int64
+	for b, h := range chainData.blockHashes {
+		// a block which lost all its logs was rewritten too
+		if hashes[b] != h && (reorg == 0 || b < reorg) {
+			reorg = b
+		}
+	}
+	chainData.blockHashes = make(map[int64]string, len(hashes))
+	for b, h := range hashes {
+		if b >= windowStart {
+			chainData.blockHashes[b] = h
+		}
+	}
+	return reorg
+}
+
+// rewind moves the worker back before the reorged block, the next scan rediscovers the tokens
+// and registers the pools of the rewritten blocks.
+func (r *RateWorker) rewind(ctx context.Context, log *zap.SugaredLogger, block int64, last int64) {
+	chainID := common.ChainBase.String()
+	chainData := r.chainData[common.ChainBase]
+	log.Warnw("reorg detected", "block", block, "lastStored", chainData.lastStoredBlock, "lastStoredBlockDb", last)
+	metrics.Reorgs.WithLabelValues(chainID).Inc()
+	metrics.ReorgDepth.WithLabelValues(chainID).Observe(float64(chainData.lastStoredBlock - block + 1))
+	if chainData.lastStoredBlock >= block {
+		chainData.lastStoredBlock = block - 1
+	}
+	if r.poolsScannedBlock >= block {
+		r.poolsScannedBlock = block - 1
+	}
+
+	removed, err := r.reorgStore.RewindSeenTokens(ctx, chainID, block)
+	if err != nil {
+		log.Errorw("error when rewind seen tokens", "block", block, "err", err)
+	}
+	for _, a := range removed {
+		delete(chainData.tokenPools, a)
+		delete(chainData.dexTokens, a)
+		delete(r.poolTokens, a)
+		delete(r.unpriced, a)
+		delete(r.supply, a)
+		delete(r.risk, a)
+		delete(r.peakLiquidity, a)
+		delete(r.pairAddresses, a)
+	}
+	r.scheduler.Untrack(removed)
+
+	// the first seen block and first minter come from the rewritten transfer logs, the registry
+	// registers the tokens again
+	rewound, err := r.reorgStore.RewindTokenMetadata(ctx, chainID, block)
+	if err != nil {
+		log.Errorw("error when rewind token metadata", "block", block, "err", err)
+	}
+	for _, a := range append(rewound, removed...) {
+		delete(r.metadata, a)
+		delete(r.decimals, a)
+		delete(r.registryRetried, a)
+	}
+
+	discoveries, err := r.reorgStore.DeleteTokenDiscoveriesFrom(ctx, chainID, block)
+	if err != nil {
+		log.Errorw("error when delete token discoveries", "block", block, "err", err)
+	}
+	if err := r.reorgStore.DeletePoolsFrom(ctx, chainID, block); err != nil {
+		log.Errorw("error when delete pools", "block", block, "err", err)
+	}
+	r.rebuildCandles(ctx, log, block)
+	log.Infow("rewound reorged blocks", "block", block, "tokens", len(removed), "metadata", len(rewound),
+		"discoveries", discoveries)
+}
+
+// rebuildCandles deletes the backfilled candles holding the reorged blocks and builds them again
+// from the rewritten trade logs.
+func (r *RateWorker) rebuildCandles(ctx context.Context, log *zap.SugaredLogger, block int64) {
+	chainID := common.ChainBase.String()
+	rewound, err := r.reorgStore.RewindCandles(ctx, chainID, block)
+	if err != nil {
+		log.Errorw("error when rewind candles", "block", block, "err", err)
+		return
+	}
+	anchors := make([]string, 0, len(r.candleAnchors))
+	for _, a := range r.candleAnchors {
+		anchors = append(anchors, a.Address)
+	}
+	for interval, last := range rewound {
+		from := block - block%interval
+		trades, err := r.reorgStore.GetAnchorTradesByRange(ctx, db.BaseTradeLogs, from, last, anchors)
+		if err != nil {
+			log.Errorw("error when get anchor trades", "from", from, "to", last, "err", err)
+			continue
+		}
+		bounds, err := tradeAmountBounds(ctx, r.metadataStore, trades)
+		if err != nil {
+			log.Errorw("error when get token metadata", "from", from, "to", last, "err", err)
+			continue
+		}
+		candles := buildCandles(chainID, trades, r.candleAnchors, interval, bounds)
+		if err := r.reorgStore.SaveCandles(ctx, candles); err != nil {
+			log.Errorw("error when save candles", "from", from, "to", last, "err", err)
+			continue
+		}
+		log.Infow("rebuilt reorged candles", "interval", interval, "from", from, "to", last, "candles", len(candles))
+	}
+}
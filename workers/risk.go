@@ -0,0 +1,214 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+// trades and transfers are analyzed over the last riskBlockRange blocks (~1 day on base)
+const riskBlockRange = 43200
+const riskChunk = 200
+
+// the risk of the new tokens is assessed every cycle, after a failure it waits riskRetryInterval
+const riskRetryInterval = 5 * time.Minute
+
+const (
+	// enough buys to expect some sells
+	riskMinBuys = 20
+	// below this sells to buys ratio sells are likely blocked
+	riskMinSellRatio = 0.05
+	// a liquidity below this share of its peak has been pulled
+	riskLiquidityPulledRatio = 0.2
+	riskHighTop10Share       = 0.9
+	riskMediumTop10Share     = 0.5
+	riskMinHolders           = 50
+	// transfers from a single sender other than the pools, only the owner can transfer
+	riskOwnerOnlyMinTransfers = 10
+)
+
+const (
+	RiskReasonNoSells            = "no_sells"
+	RiskReasonLowSellRatio       = "low_sell_ratio"
+	RiskReasonLiquidityPulled    = "liquidity_pulled"
+	RiskReasonOwnerOnlyTransfers = "owner_only_transfers"
+	RiskReasonConcentrated       = "concentrated_holders"
+	RiskReasonFewHolders         = "few_holders"
+)
+
+// SetRiskStore makes the worker compute the risk label of the dex tokens. The tokens riskier than
+// maxLabel aren't published, no label is filtered if maxLabel is 0.
+func (r *RateWorker) SetRiskStore(store db.RiskStore, maxLabel common.RiskLabel) {
+	r.riskStore = store
+	r.riskMaxLabel = maxLabel
+}
+
+// observePeakLiquidity keeps the highest liquidity seen of a token to detect liquidity pulls.
+func (r *RateWorker) observePeakLiquidity(address string, liquidity float64) {
+	if r.riskStore == nil {
+		return
+	}
+	if liquidity > r.peakLiquidity[address] {
+		r.peakLiquidity[address] = liquidity
+	}
+}
+
+// assessRisk labels a token from its signals, the label is the worst of the reasons.
+func assessRisk(sides common.TradeSides, holders common.HolderConcentration, senders common.TransferSenders,
+	liquidity, peakLiquidity float64) common.TokenRisk {
+	risk := common.TokenRisk{
+		Label:      common.RiskLabelLow,
+		Reasons:    []string{},
+		Buys:       sides.Buys,
+		Sells:      sides.Sells,
+		Holders:    holders.Holders,
+		Top10Share: holders.Top10Share,
+	}
+	raise := func(label common.RiskLabel, reason string) {
+		risk.Reasons = append(risk.Reasons, reason)
+		if label > risk.Label {
+			risk.Label = label
+		}
+	}
+	if sides.Buys >= riskMinBuys {
+		if sides.Sells == 0 {
+			raise(common.RiskLabelHoneypot, RiskReasonNoSells)
+		} else if float64(sides.Sells)/float64(sides.Buys) < riskMinSellRatio {
+			raise(common.RiskLabelHigh, RiskReasonLowSellRatio)
+		}
+	}
+	if peakLiquidity > 0 && liquidity < peakLiquidity*riskLiquidityPulledRatio {
+		raise(common.RiskLabelHigh, RiskReasonLiquidityPulled)
+	}
+	if senders.Transfers >= riskOwnerOnlyMinTransfers && senders.Senders <= 1 {
+		raise(common.RiskLabelHigh, RiskReasonOwnerOnlyTransfers)
+	}
+	if holders.Top10Share >= riskHighTop10Share {
+		raise(common.RiskLabelHigh, RiskReasonConcentrated)
+	} else if holders.Top10Share >= riskMediumTop10Share {
+		raise(common.RiskLabelMedium, RiskReasonConcentrated)
+	}
+	if holders.Holders > 0 && holders.Holders < riskMinHolders {
+		raise(common.RiskLabelMedium, RiskReasonFewHolders)
+	}
+	return risk
+}
+
+// updateRisk computes the risk of the published base tokens.
+func (r *RateWorker) updateRisk(ctx context.Context, log *zap.SugaredLogger) {
+	if r.riskStore == nil {
+		return
+	}
+	owned := r.ownedTokens()
+	// the peaks of the tokens which aren't published anymore are dropped with their risk
+	for a := range r.peakLiquidity {
+		if _, exist := owned[a]; !exist {
+			delete(r.peakLiquidity, a)
+		}
+	}
+	tokens := make([]string, 0, len(owned))
+	for a := range owned {
+		tokens = append(tokens, a)
+	}
+	risk, err := r.assessTokens(ctx, tokens)
+	if err != nil {
+		log.Errorw("error when assess risk", "err", err)
+		r.riskFailedAt = time.Now()
+		return
+	}
+	r.risk = risk
+	log.Infow("finish update risk", "tokens", len(tokens))
+}
+
+// assessNewTokens assesses the tokens priced since the last full cycle, so they aren't
+// published before their risk is known.
+func (r *RateWorker) assessNewTokens(ctx context.Context, log *zap.SugaredLogger) {
+	if r.riskStore == nil || time.Since(r.riskFailedAt) < riskRetryInterval {
+		return
+	}
+	tokens := []string{}
+	for a := range r.ownedTokens() {
+		if _, assessed := r.risk[a]; !assessed {
+			tokens = append(tokens, a)
+		}
+	}
+	if len(tokens) == 0 {
+		return
+	}
+	risk, err := r.assessTokens(ctx, tokens)
+	if err != nil {
+		log.Errorw("error when assess risk of new tokens", "tokens", len(tokens), "err", err)
+		r.riskFailedAt = time.Now()
+		return
+	}
+	if r.risk == nil {
+		r.risk = make(map[string]common.TokenRisk, len(risk))
+	}
+	for a, t := range risk {
+		r.risk[a] = t
+	}
+}
+
+func (r *RateWorker) assessTokens(ctx context.Context, tokens []string) (map[string]common.TokenRisk, error) {
+	chainData := r.chainData[common.ChainBase]
+	owned := r.ownedTokens()
+	fromBlock := chainData.lastStoredBlock - riskBlockRange
+	risk := make(map[string]common.TokenRisk, len(tokens))
+	for bg := 0; bg < len(tokens); bg += riskChunk {
+		end := bg + riskChunk
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunk := tokens[bg:end]
+		// the pools hold and send the tokens of every trade, they aren't holders nor owners
+		excluded := append([]string{}, burnAddresses...)
+		excluded = append(excluded, r.lockerAddresses...)
+		for _, a := range chunk {
+			excluded = append(excluded, r.pairAddresses[a]...)
+			if t, exist := r.poolTokens[a]; exist {
+				excluded = append(excluded, t.PairAddress)
+			}
+		}
+		sides, err := r.riskStore.GetTradeSides(ctx, db.BaseTradeLogs, chunk, fromBlock)
+		if err != nil {
+			return nil, fmt.Errorf("get trade sides: %w", err)
+		}
+		holders, err := r.riskStore.GetHolderConcentration(ctx, db.BaseTransferLogs, chunk, excluded)
+		if err != nil {
+			return nil, fmt.Errorf("get holder concentration: %w", err)
+		}
+		senders, err := r.riskStore.GetTransferSenders(ctx, db.BaseTransferLogs, chunk, fromBlock, excluded)
+		if err != nil {
+			return nil, fmt.Errorf("get transfer senders: %w", err)
+		}
+		for _, a := range chunk {
+			risk[a] = assessRisk(sides[a], holders[a], senders[a], owned[a].LiquidityUsd, r.peakLiquidity[a])
+		}
+	}
+	return risk, nil
+}
+
+// applyRisk sets the risk of a dex token, it returns false if the token is too risky to publish.
+// With a max label an unassessed token is held back until it's assessed.
+func (r *RateWorker) applyRisk(t common.Token) (common.Token, bool) {
+	risk, exist := r.risk[strings.ToLower(t.Address)]
+	if !exist {
+		if r.riskStore != nil && r.riskMaxLabel != 0 {
+			metrics.TokensFiltered.WithLabelValues("risk_unassessed").Inc()
+			return t, false
+		}
+		return t, true
+	}
+	t.RiskLabel, t.RiskReasons = risk.Label, risk.Reasons
+	if r.riskMaxLabel != 0 && risk.Label > r.riskMaxLabel {
+		metrics.TokensFiltered.WithLabelValues("risk_" + risk.Label.String()).Inc()
+		return t, false
+	}
+	return t, true
+}
@@ -0,0 +1,111 @@
+package workers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+func TestAssessRisk(t *testing.T) {
+	healthy := common.HolderConcentration{Holders: 1000, Top10Share: 0.2}
+	tests := []struct {
+		name          string
+		sides         common.TradeSides
+		holders       common.HolderConcentration
+		senders       common.TransferSenders
+		liquidity     float64
+		peakLiquidity float64
+		label         common.RiskLabel
+		reasons       []string
+	}{
+		{
+			name:    "healthy token",
+			sides:   common.TradeSides{Buys: 100, Sells: 80},
+			holders: healthy,
+			senders: common.TransferSenders{Transfers: 100, Senders: 40},
+			label:   common.RiskLabelLow,
+			reasons: []string{},
+		},
+		{
+			name:    "too few buys to expect sells",
+			sides:   common.TradeSides{Buys: riskMinBuys - 1},
+			holders: healthy,
+			label:   common.RiskLabelLow,
+			reasons: []string{},
+		},
+		{
+			name:    "no sells",
+			sides:   common.TradeSides{Buys: riskMinBuys},
+			holders: healthy,
+			label:   common.RiskLabelHoneypot,
+			reasons: []string{RiskReasonNoSells},
+		},
+		{
+			name:    "low sell ratio",
+			sides:   common.TradeSides{Buys: 100, Sells: 4},
+			holders: healthy,
+			label:   common.RiskLabelHigh,
+			reasons: []string{RiskReasonLowSellRatio},
+		},
+		{
+			name:          "liquidity pulled",
+			holders:       healthy,
+			liquidity:     1000,
+			peakLiquidity: 100000,
+			label:         common.RiskLabelHigh,
+			reasons:       []string{RiskReasonLiquidityPulled},
+		},
+		{
+			name:          "liquidity down but not pulled",
+			holders:       healthy,
+			liquidity:     50000,
+			peakLiquidity: 100000,
+			label:         common.RiskLabelLow,
+			reasons:       []string{},
+		},
+		{
+			name:    "owner only transfers",
+			holders: healthy,
+			senders: common.TransferSenders{Transfers: riskOwnerOnlyMinTransfers, Senders: 1},
+			label:   common.RiskLabelHigh,
+			reasons: []string{RiskReasonOwnerOnlyTransfers},
+		},
+		{
+			name:    "medium concentration",
+			holders: common.HolderConcentration{Holders: 1000, Top10Share: riskMediumTop10Share},
+			label:   common.RiskLabelMedium,
+			reasons: []string{RiskReasonConcentrated},
+		},
+		{
+			name:    "high concentration",
+			holders: common.HolderConcentration{Holders: 1000, Top10Share: riskHighTop10Share},
+			label:   common.RiskLabelHigh,
+			reasons: []string{RiskReasonConcentrated},
+		},
+		{
+			name:    "few holders",
+			holders: common.HolderConcentration{Holders: riskMinHolders - 1, Top10Share: 0.2},
+			label:   common.RiskLabelMedium,
+			reasons: []string{RiskReasonFewHolders},
+		},
+		{
+			name:    "the label is the worst reason",
+			sides:   common.TradeSides{Buys: riskMinBuys},
+			holders: common.HolderConcentration{Holders: 10, Top10Share: riskHighTop10Share},
+			label:   common.RiskLabelHoneypot,
+			reasons: []string{RiskReasonNoSells, RiskReasonConcentrated, RiskReasonFewHolders},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risk := assessRisk(tt.sides, tt.holders, tt.senders, tt.liquidity, tt.peakLiquidity)
+			if risk.Label != tt.label {
+				t.Errorf("label %s, want %s", risk.Label, tt.label)
+			}
+			if !reflect.DeepEqual(risk.Reasons, tt.reasons) {
+				t.Errorf("reasons %v, want %v", risk.Reasons, tt.reasons)
+			}
+		})
+	}
+}
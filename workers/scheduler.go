@@ -0,0 +1,156 @@
+package workers
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+// trade and demand counts are measured over the last activityBlockRange blocks (~1 hour on base)
+const activityBlockRange = 1800
+const hotMinTrades = 500
+const hotMinDemand = 100
+const warmMinTrades = 50
+const warmMinDemand = 10
+const warmMinLiquidity = 250000
+
+type RefreshIntervals map[common.RefreshTier]time.Duration
+
+type TokenActivity struct {
+	Trades       int64
+	LiquidityUsd float64
+	Demand       int64
+}
+
+type scheduleEntry struct {
+	tier          common.RefreshTier
+	activity      TokenActivity
+	lastRefreshed time.Time
+}
+
+// RefreshScheduler decides which tokens are due for a price refresh, so that active tokens
+// are refreshed often and dormant ones rarely, without going over the provider request budget.
+type RefreshScheduler struct {
+	mu        sync.Mutex
+	intervals RefreshIntervals
+	budget    int
+	entries   map[string]*scheduleEntry
+	demand    map[string]int64
+}
+
+// NewRefreshScheduler creates a scheduler, budget is the max number of provider requests per minute.
+func NewRefreshScheduler(intervals RefreshIntervals, budget int) *RefreshScheduler {
+	return &RefreshScheduler{
+		intervals: intervals,
+		budget:    budget,
+		entries:   make(map[string]*scheduleEntry),
+		demand:    make(map[string]int64),
+	}
+}
+
+func (s *RefreshScheduler) entry(address string) *scheduleEntry {
+	e, exist := s.entries[address]
+	if !exist {
+		e = &scheduleEntry{tier: common.RefreshTierCold}
+		s.entries[address] = e
+	}
+	return e
+}
+
+// Track adds tokens to the schedule, new tokens are due immediately.
+func (s *RefreshScheduler) Track(addresses []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range addresses {
+		s.entry(strings.ToLower(a))
+	}
+}
+
+// Untrack removes tokens from the schedule.
+func (s *RefreshScheduler) Untrack(addresses []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range addresses {
+		delete(s.entries, strings.ToLower(a))
+		delete(s.demand, strings.ToLower(a))
+	}
+}
+
+// RecordDemand counts a consumer request for the token.
+func (s *RefreshScheduler) RecordDemand(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.demand[strings.ToLower(address)]++
+}
+
+func (s *RefreshScheduler) ObserveLiquidity(address string, liquidityUsd float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entry(strings.ToLower(address)).activity.LiquidityUsd = liquidityUsd
+}
+
+// UpdateActivity recomputes the tier of every tracked token from its recent trades, liquidity
+// and the demand recorded since the previous update.
+func (s *RefreshScheduler) UpdateActivity(trades map[string]int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for a, e := range s.entries {
+		e.activity.Trades = trades[a]
+		e.activity.Demand = s.demand[a]
+		e.tier = tierOf(e.activity)
+	}
+	s.demand = make(map[string]int64)
+}
+
+func tierOf(a TokenActivity) common.RefreshTier {
+	switch {
+	case a.Trades >= hotMinTrades || a.Demand >= hotMinDemand:
+		return common.RefreshTierHot
+	case a.Trades >= warmMinTrades || a.Demand >= warmMinDemand || a.LiquidityUsd >= warmMinLiquidity:
+		return common.RefreshTierWarm
+	case a.Trades > 0 || a.Demand > 0 || a.LiquidityUsd >= minLiquidity:
+		return common.RefreshTierCold
+	default:
+		return common.RefreshTierDormant
+	}
+}
+
+// Due returns the tokens whose refresh interval has elapsed, hottest and most stale first.
+func (s *RefreshScheduler) Due(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []string{}
+	for a, e := range s.entries {
+		if now.Sub(e.lastRefreshed) >= s.intervals[e.tier] {
+			due = append(due, a)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		ei, ej := s.entries[due[i]], s.entries[due[j]]
+		if ei.tier != ej.tier {
+			return ei.tier < ej.tier
+		}
+		return ei.lastRefreshed.Before(ej.lastRefreshed)
+	})
+	return due
+}
+
+func (s *RefreshScheduler) MarkRefreshed(addresses []string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range addresses {
+		s.entry(strings.ToLower(a)).lastRefreshed = now
+	}
+}
+
+// MaxRequests returns how many provider requests can be made in a tick without going over budget.
+func (s *RefreshScheduler) MaxRequests(tick time.Duration) int {
+	n := int(int64(s.budget) * int64(tick) / int64(time.Minute))
+	if n < 1 {
+		return 1
+	}
+	return n
+}
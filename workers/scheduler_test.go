@@ -0,0 +1,104 @@
+package workers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+func TestTierOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		activity TokenActivity
+		want     common.RefreshTier
+	}{
+		{"no activity", TokenActivity{}, common.RefreshTierDormant},
+		{"liquidity below the min", TokenActivity{LiquidityUsd: minLiquidity - 1}, common.RefreshTierDormant},
+		{"one trade", TokenActivity{Trades: 1}, common.RefreshTierCold},
+		{"one request", TokenActivity{Demand: 1}, common.RefreshTierCold},
+		{"min liquidity", TokenActivity{LiquidityUsd: minLiquidity}, common.RefreshTierCold},
+		{"warm trades", TokenActivity{Trades: warmMinTrades}, common.RefreshTierWarm},
+		{"warm demand", TokenActivity{Demand: warmMinDemand}, common.RefreshTierWarm},
+		{"warm liquidity", TokenActivity{LiquidityUsd: warmMinLiquidity}, common.RefreshTierWarm},
+		{"hot trades", TokenActivity{Trades: hotMinTrades}, common.RefreshTierHot},
+		{"hot demand", TokenActivity{Demand: hotMinDemand, LiquidityUsd: 1}, common.RefreshTierHot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tierOf(tt.activity); got != tt.want {
+				t.Fatalf("tierOf(%+v) = %s, want %s", tt.activity, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshSchedulerDue(t *testing.T) {
+	intervals := RefreshIntervals{
+		common.RefreshTierHot:     time.Minute,
+		common.RefreshTierWarm:    5 * time.Minute,
+		common.RefreshTierCold:    30 * time.Minute,
+		common.RefreshTierDormant: 6 * time.Hour,
+	}
+	start := time.Unix(1_700_000_000, 0)
+	s := NewRefreshScheduler(intervals, 300)
+	s.Track([]string{"0xHot", "0xWarm", "0xCold", "0xDormant"})
+	for i := 0; i < hotMinDemand; i++ {
+		s.RecordDemand("0xhot")
+	}
+	s.UpdateActivity(map[string]int64{"0xwarm": warmMinTrades, "0xcold": 1})
+
+	if got := s.Due(start); len(got) != 4 {
+		t.Fatalf("new tokens aren't due: %v", got)
+	}
+	s.MarkRefreshed([]string{"0xhot", "0xwarm", "0xcold", "0xdormant"}, start)
+
+	tests := []struct {
+		after time.Duration
+		want  []string
+	}{
+		{30 * time.Second, []string{}},
+		{time.Minute, []string{"0xhot"}},
+		{5 * time.Minute, []string{"0xhot", "0xwarm"}},
+		{30 * time.Minute, []string{"0xhot", "0xwarm", "0xcold"}},
+		{6 * time.Hour, []string{"0xhot", "0xwarm", "0xcold", "0xdormant"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.after.String(), func(t *testing.T) {
+			if got := s.Due(start.Add(tt.after)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Due = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// within a tier the most stale token is first
+	s.MarkRefreshed([]string{"0xwarm"}, start.Add(time.Minute))
+	s.UpdateActivity(map[string]int64{"0xhot": hotMinTrades, "0xwarm": hotMinTrades, "0xcold": 1})
+	if got, want := s.Due(start.Add(time.Hour)), []string{"0xhot", "0xwarm", "0xcold"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Due = %v, want %v", got, want)
+	}
+	s.Untrack([]string{"0xHOT"})
+	if got, want := s.Due(start.Add(time.Hour)), []string{"0xwarm", "0xcold"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Due after untrack = %v, want %v", got, want)
+	}
+}
+
+func TestRefreshSchedulerMaxRequests(t *testing.T) {
+	tests := []struct {
+		budget int
+		tick   time.Duration
+		want   int
+	}{
+		{300, time.Minute, 300},
+		{300, 10 * time.Second, 50},
+		{300, 2 * time.Minute, 600},
+		{3, 10 * time.Second, 1},
+		{0, time.Minute, 1},
+	}
+	for _, tt := range tests {
+		if got := NewRefreshScheduler(nil, tt.budget).MaxRequests(tt.tick); got != tt.want {
+			t.Errorf("MaxRequests(budget %d, tick %s) = %d, want %d", tt.budget, tt.tick, got, tt.want)
+		}
+	}
+}
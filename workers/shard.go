@@ -0,0 +1,149 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
+	"github.com/kv-base-hack/base-token-rate/lib/tracing"
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
+	"github.com/kv-base-hack/common/utils"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// a shard snapshot expires if its replica stops publishing, so the merger drops it
+const shardSnapshotTTL = 2 * time.Minute
+
+// Sharding splits the tokens between replicas, each replica refreshes and publishes its own shard.
+type Sharding interface {
+	ID() string
+	Members() []string
+	Owns(address string) bool
+}
+
+func shardPricesKey(id string) string {
+	return RatePricesKey + ":shard:" + id
+}
+
+// SetSharding makes the worker refresh only the tokens of its shard and publish them to the shard key,
+// the SnapshotMerger assembles the shards into the published snapshot.
+func (r *RateWorker) SetSharding(sharding Sharding) {
+	r.sharding = sharding
+}
+
+func (r *RateWorker) owns(address string) bool {
+	return r.sharding == nil || r.sharding.Owns(address)
+}
+
+// SnapshotMerger runs on the leader and publishes the union of all shard snapshots.
+type SnapshotMerger struct {
+	log        *zap.SugaredLogger
+	interval   time.Duration
+	client     *redis.Client
+	inMemDB    kv.Store
+	sharding   Sharding
+	leadership Leadership
+	status     StatusReporter
+	observers  []SnapshotObserver
+}
+
+func NewSnapshotMerger(log *zap.SugaredLogger, interval time.Duration, client *redis.Client, inMemDB kv.Store,
+	sharding Sharding, leadership Leadership) *SnapshotMerger {
+	return &SnapshotMerger{
+		log:        log,
+		interval:   interval,
+		client:     client,
+		inMemDB:    inMemDB,
+		sharding:   sharding,
+		leadership: leadership,
+		status:     nopStatusReporter{},
+	}
+}
+
+func (m *SnapshotMerger) Run() {
+	ticker := time.NewTicker(m.interval)
+	for ; ; <-ticker.C {
+		if !m.leadership.IsLeader() {
+			continue
+		}
+		if err := m.merge(); err != nil {
+			m.status.CycleFailed(SnapshotMergerName, err)
+			continue
+		}
+		m.status.CycleSucceeded(SnapshotMergerName)
+	}
+}
+
+func (m *SnapshotMerger) merge() error {
+	id := utils.RandomString(21)
+	ctx, span := tracing.Tracer().Start(m.leadership.Context(), "snapshot_merger.cycle",
+		trace.WithAttributes(attribute.String("cycle.id", id)))
+	log := m.log.With("merge", id, "trace_id", span.SpanContext().TraceID().String())
+	defer func(begin time.Time) {
+		span.End()
+		metrics.CycleDuration.WithLabelValues(SnapshotMergerName).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	members := m.sharding.Members()
+	keys := make([]string, 0, len(members))
+	for _, id := range members {
+		keys = append(keys, shardPricesKey(id))
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+	values, err := m.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Errorw("error when get shard snapshots", "keys", keys, "err", err)
+		return err
+	}
+
+	seen := map[string]bool{}
+	tokens := []common.Token{}
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			log.Warnw("missing shard snapshot", "replica", members[i])
+			continue
+		}
+		var shard []common.Token
+		if err := json.Unmarshal([]byte(data), &shard); err != nil {
+			log.Errorw("error when unmarshal shard snapshot", "replica", members[i], "err", err)
+			continue
+		}
+		for _, t := range shard {
+			// only the leader carries the cex tokens, but a token moving to another shard is in both
+			// snapshots until its old owner publishes again, keep one copy of each price
+			key := t.SourcePrice.String() + ":" + t.ChainID + ":" + strings.ToLower(t.Address)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			tokens = append(tokens, t)
+		}
+	}
+
+	observeTokensPublished(tokens)
+
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		log.Errorw("error when marshal data", "err", err)
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		log.Warnw("merge cancelled before publish", "err", err)
+		return err
+	}
+	// no expire
+	if err := m.inMemDB.Set(RatePricesKey, data, 0); err != nil {
+		log.Errorw("error when set key", "key", RatePricesKey, "err", err)
+		return err
+	}
+	notifyObservers(ctx, m.observers, tokens)
+	log.Infow("finish merge shard snapshots", "shards", len(members), "tokens", len(tokens))
+	return nil
+}
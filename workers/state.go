@@ -0,0 +1,132 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"go.uber.org/zap"
+)
+
+const RateWorkerStateName = "rate_worker"
+
+type chainState struct {
+	LastStoredBlock int64                   `json:"lastStoredBlock"`
+	TokenPools      map[string]int          `json:"tokenPools"`
+	DexTokens       map[string]common.Token `json:"dexTokens"`
+	BlockHashes     map[int64]string        `json:"blockHashes"`
+	AnchorRates     map[string]float64      `json:"anchorRates,omitempty"`
+}
+
+// shardStateName is the state of the dex rates of a shard, the discovery state is the leader's.
+func shardStateName(id string) string {
+	return RateWorkerStateName + ":shard:" + id
+}
+
+// checkpoint stores the discovery state so a restart doesn't rescan maxBlockRange blocks,
+// the last dex rates are kept too so a standby taking over publishes a full snapshot, their updatedAt
+// tells the restored rates from the refreshed ones.
+// With sharding the leader stores the discovery state and every replica the dex rates of its shard.
+func (r *RateWorker) checkpoint(ctx context.Context, log *zap.SugaredLogger, leader bool) {
+	if leader {
+		state := map[common.Chain]chainState{}
+		for chain, v := range r.chainData {
+			s := chainState{
+				LastStoredBlock: v.lastStoredBlock,
+				TokenPools:      v.tokenPools,
+				BlockHashes:     v.blockHashes,
+				AnchorRates:     v.anchorRates,
+			}
+			if r.sharding == nil {
+				s.DexTokens = v.dexTokens
+			}
+			state[chain] = s
+		}
+		r.saveState(ctx, log, RateWorkerStateName, state)
+	}
+	if r.sharding != nil {
+		state := map[common.Chain]chainState{}
+		for chain, v := range r.chainData {
+			state[chain] = chainState{DexTokens: v.dexTokens}
+		}
+		r.saveState(ctx, log, shardStateName(r.sharding.ID()), state)
+	}
+}
+
+func (r *RateWorker) saveState(ctx context.Context, log *zap.SugaredLogger, name string, state map[common.Chain]chainState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		log.Errorw("error when marshal worker state", "err", err)
+		return
+	}
+	if err := r.db.SaveWorkerState(ctx, name, data); err != nil {
+		log.Errorw("error when save worker state", "name", name, "err", err)
+	}
+}
+
+func (r *RateWorker) loadState(ctx context.Context, log *zap.SugaredLogger, name string) map[common.Chain]chainState {
+	data, err := r.db.GetWorkerState(ctx, name)
+	if err != nil {
+		log.Errorw("error when get worker state", "name", name, "err", err)
+		return nil
+	}
+	if data == nil {
+		log.Infow("no worker state to restore", "name", name)
+		return nil
+	}
+	state := map[common.Chain]chainState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Errorw("error when unmarshal worker state", "name", name, "err", err)
+		return nil
+	}
+	return state
+}
+
+// restore loads the discovery state of the leader, and the dex rates of the shard with sharding.
+func (r *RateWorker) restore(ctx context.Context, log *zap.SugaredLogger) {
+	r.restoreDiscovery(ctx, log)
+	if r.sharding == nil {
+		return
+	}
+	for chain, s := range r.loadState(ctx, log, shardStateName(r.sharding.ID())) {
+		v, exist := r.chainData[chain]
+		if !exist {
+			continue
+		}
+		for a, t := range s.DexTokens {
+			v.dexTokens[a] = t
+		}
+	}
+}
+
+// restoreDiscovery loads the discovery state of the leader, the shard replicas load it every full cycle
+// to follow the tokens the leader discovers.
+func (r *RateWorker) restoreDiscovery(ctx context.Context, log *zap.SugaredLogger) {
+	for chain, s := range r.loadState(ctx, log, RateWorkerStateName) {
+		v, exist := r.chainData[chain]
+		if !exist {
+			continue
+		}
+		v.lastStoredBlock = s.LastStoredBlock
+		// the leader may have rewound tokens since, its state replaces the known ones
+		v.tokenPools = make(map[string]int, len(s.TokenPools))
+		addresses := make([]string, 0, len(s.TokenPools))
+		for a, p := range s.TokenPools {
+			v.tokenPools[a] = p
+			addresses = append(addresses, a)
+		}
+		for a, t := range s.DexTokens {
+			v.dexTokens[a] = t
+		}
+		v.blockHashes = make(map[int64]string, len(s.BlockHashes))
+		for b, h := range s.BlockHashes {
+			v.blockHashes[b] = h
+		}
+		v.anchorRates = make(map[string]float64, len(s.AnchorRates))
+		for a, rate := range s.AnchorRates {
+			v.anchorRates[a] = rate
+		}
+		r.scheduler.Track(addresses)
+		log.Infow("restored worker state", "chain", chain, "lastStoredBlock", s.LastStoredBlock, "tokens", len(s.TokenPools))
+	}
+}
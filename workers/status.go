@@ -0,0 +1,40 @@
+package workers
+
+import (
+	"errors"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+)
+
+const (
+	RateWorkerName      = "rate_worker"
+	TokenInfoWorkerName = "token_info_worker"
+	SnapshotMergerName  = "snapshot_merger"
+)
+
+var errAllBatchesFailed = errors.New("all rate provider batches failed")
+
+// StatusReporter is told about the outcome of each worker cycle, it backs the readiness and status endpoints.
+type StatusReporter interface {
+	CycleSucceeded(worker string)
+	CycleFailed(worker string, err error)
+	SetBlockLag(chain common.Chain, lag int64)
+}
+
+type nopStatusReporter struct{}
+
+func (nopStatusReporter) CycleSucceeded(string)           {}
+func (nopStatusReporter) CycleFailed(string, error)       {}
+func (nopStatusReporter) SetBlockLag(common.Chain, int64) {}
+
+func (r *RateWorker) SetStatusReporter(status StatusReporter) {
+	r.status = status
+}
+
+func (t *TokenInfoWorker) SetStatusReporter(status StatusReporter) {
+	t.status = status
+}
+
+func (m *SnapshotMerger) SetStatusReporter(status StatusReporter) {
+	m.status = status
+}
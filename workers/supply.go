@@ -0,0 +1,84 @@
+package workers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/storage/db"
+	"go.uber.org/zap"
+)
+
+const supplyChunk = 500
+
+// a transfer logs supply this many times the on chain total supply comes from raw amounts
+const supplyMaxOnChainRatio = 1000
+
+// transfers to these addresses are burned
+var burnAddresses = []string{
+	"0x0000000000000000000000000000000000000000",
+	"0x000000000000000000000000000000000000dead",
+}
+
+// SetSupplyStore makes the worker publish the supply, market cap and fdv of the dex tokens,
+// the balance of the locker addresses isn't circulating.
+func (r *RateWorker) SetSupplyStore(store db.SupplyStore, lockerAddresses []string) {
+	r.supplyStore = store
+	r.lockerAddresses = lockerAddresses
+}
+
+// updateSupply computes the supply of the published base tokens from the transfer logs.
+func (r *RateWorker) updateSupply(ctx context.Context, log *zap.SugaredLogger) {
+	if r.supplyStore == nil {
+		return
+	}
+	tokens := []string{}
+	for a := range r.ownedTokens() {
+		tokens = append(tokens, a)
+	}
+	supply := make(map[string]common.TokenSupply, len(tokens))
+	for bg := 0; bg < len(tokens); bg += supplyChunk {
+		end := bg + supplyChunk
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunk, err := r.supplyStore.GetTokenSupply(ctx, db.BaseTransferLogs, tokens[bg:end], burnAddresses, r.lockerAddresses)
+		if err != nil {
+			log.Errorw("error when get token supply", "tokens", end-bg, "err", err)
+			return
+		}
+		for a, s := range chunk {
+			if !r.supplyScaled(a, s) {
+				log.Warnw("transfer logs supply doesn't match the on chain supply, amounts aren't in token units",
+					"token", a, "supply", s.TotalSupply)
+				continue
+			}
+			supply[a] = s
+		}
+	}
+	r.supply = supply
+	log.Infow("finish update supply", "tokens", len(tokens), "supply", len(supply))
+}
+
+// supplyScaled checks the supply from the transfer logs against the registered on chain total supply,
+// logs missing mints give a lower supply but raw amounts give one many orders of magnitude higher.
+func (r *RateWorker) supplyScaled(address string, s common.TokenSupply) bool {
+	m, exist := r.metadata[address]
+	if !exist || m.TotalSupply == nil || *m.TotalSupply <= 0 {
+		return true
+	}
+	return s.TotalSupply <= *m.TotalSupply*supplyMaxOnChainRatio
+}
+
+// applySupply sets the supply of a dex token with the market cap and fdv at its price.
+func (r *RateWorker) applySupply(t common.Token) common.Token {
+	s, exist := r.supply[strings.ToLower(t.Address)]
+	if !exist || s.TotalSupply <= 0 {
+		return t
+	}
+	t.TotalSupply = s.TotalSupply
+	t.CirculatingSupply = s.CirculatingSupply
+	t.MarketCap = t.UsdPrice * s.CirculatingSupply
+	t.Fdv = t.UsdPrice * s.TotalSupply
+	return t
+}
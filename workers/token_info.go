@@ -5,51 +5,143 @@ import (
 	"time"
 
 	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
+	"github.com/kv-base-hack/base-token-rate/lib/rateprovider/coingecko"
 	"github.com/kv-base-hack/base-token-rate/lib/rateprovider/coinmarketcap"
-	inmem "github.com/kv-base-hack/common/inmem_db"
+	"github.com/kv-base-hack/base-token-rate/lib/tracing"
+	"github.com/kv-base-hack/base-token-rate/storage/kv"
 	"github.com/kv-base-hack/common/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-const cmcTokenInfoKey = "cmc_token_info"
+// CmcTokenInfoKey is the key of the coinmarketcap listing, stored as common.RedisTokens.
+const CmcTokenInfoKey = "cmc_token_info"
+
+// leadershipCheckInterval is how often a standby token info worker checks if it became leader.
+const leadershipCheckInterval = 5 * time.Second
+
+const cmcPageLimit = int64(5000)
+const cmcAttempts = 3
+const cmcRetryBackoff = 2 * time.Second
+
+// a run which stopped early is resumed after cmcResumeInterval instead of the full duration
+const cmcResumeInterval = 10 * time.Minute
 
 type TokenInfoWorker struct {
-	log      *zap.SugaredLogger
-	duration time.Duration
-	cmc      *coinmarketcap.CoinMarketCap
-	inMemDB  inmem.Inmem
+	log        *zap.SugaredLogger
+	duration   time.Duration
+	cmc        *coinmarketcap.CoinMarketCap
+	inMemDB    kv.Store
+	leadership Leadership
+	status     StatusReporter
+
+	creditBudget int
+	metadata     map[int]common.CoinMarketCapMetadata
+	coingecko    *coingecko.CoinGecko
+	// the coingecko coin list is cached for coingeckoListTTL
+	coingeckoCoins    map[string]map[string]common.CoinGeckoCoin
+	coingeckoLoadedAt time.Time
+	// a run which stopped early is resumed from resumeStart with the tokens it already got
+	resumeStart  int64
+	resumeTokens []common.RedisTokenInfo
+	resumeAt     time.Time
 }
 
-func NewTokenInfoWorker(log *zap.SugaredLogger, duration time.Duration, key string, url string, inMemDB inmem.Inmem) *TokenInfoWorker {
+func NewTokenInfoWorker(log *zap.SugaredLogger, duration time.Duration, key string, url string, inMemDB kv.Store,
+	leadership Leadership) *TokenInfoWorker {
 	return &TokenInfoWorker{
-		log:      log,
-		duration: duration,
-		cmc:      coinmarketcap.NewCoinMarketCap(log, key, url),
-		inMemDB:  inMemDB,
+		log:        log,
+		duration:   duration,
+		cmc:        coinmarketcap.NewCoinMarketCap(log, key, url),
+		inMemDB:    inMemDB,
+		leadership: leadership,
+		status:     nopStatusReporter{},
+		metadata:   make(map[int]common.CoinMarketCapMetadata),
 	}
 }
 
+// SetCoinGecko adds the coingecko ids to the token metadata.
+func (t *TokenInfoWorker) SetCoinGecko(cg *coingecko.CoinGecko) {
+	t.coingecko = cg
+}
+
+// SetCreditBudget limits the coinmarketcap credits a run uses, 0 is unlimited.
+func (t *TokenInfoWorker) SetCreditBudget(budget int) {
+	t.creditBudget = budget
+}
+
 func (t *TokenInfoWorker) Run() {
-	ticker := time.NewTicker(t.duration)
+	var lastProcess time.Time
+	ticker := time.NewTicker(leadershipCheckInterval)
 	for ; ; <-ticker.C {
-		t.process()
+		if !t.leadership.IsLeader() {
+			lastProcess = time.Time{}
+			t.resumeStart, t.resumeTokens = 0, nil
+			continue
+		}
+		interval := t.duration
+		if t.resumeStart > 0 {
+			interval = cmcResumeInterval
+		}
+		if time.Since(lastProcess) < interval {
+			continue
+		}
+		lastProcess = time.Now()
+		if err := t.process(); err != nil {
+			t.status.CycleFailed(TokenInfoWorkerName, err)
+			continue
+		}
+		t.status.CycleSucceeded(TokenInfoWorkerName)
 	}
 }
 
-func (t *TokenInfoWorker) process() {
+func (t *TokenInfoWorker) process() error {
+	id := utils.RandomString(22)
+	ctx, span := tracing.Tracer().Start(t.leadership.Context(), "token_info_worker.cycle",
+		trace.WithAttributes(attribute.String("cycle.id", id)))
+	defer func(begin time.Time) {
+		span.End()
+		metrics.CycleDuration.WithLabelValues(TokenInfoWorkerName).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	log := t.log.With("token_info", id, "trace_id", span.SpanContext().TraceID().String())
 	start := int64(1)
-	limit := int64(5000)
 	tokenInfo := []common.RedisTokenInfo{}
-	log := t.log.With("token_info", utils.RandomString(22))
+	if t.resumeStart > 0 && time.Since(t.resumeAt) > t.duration {
+		// the listing moved on, its pages would be merged with stale ones
+		log.Warnw("drop stale resume state", "start", t.resumeStart, "since", t.resumeAt)
+		t.resumeStart, t.resumeTokens = 0, nil
+	}
+	if t.resumeStart > 0 {
+		start, tokenInfo = t.resumeStart, t.resumeTokens
+	}
+	credits := 0
+	complete := false
+	var pageErr error
 	for {
-		cmc, err := t.cmc.GetTokenInfo(start, limit)
+		if t.creditBudget > 0 && credits >= t.creditBudget {
+			log.Warnw("coinmarketcap credit budget used, resume next run", "credits", credits, "start", start)
+			break
+		}
+		var cmc common.CoinMarketCapTokenInfo
+		err := retry(log, "listings", func() (err error) {
+			cmc, err = t.cmc.GetTokenInfo(start, cmcPageLimit)
+			return err
+		})
 		if err != nil {
-			log.Errorw("error when get coinmarket cap token info", "err", err)
-			return
+			log.Errorw("error when get coinmarket cap token info", "start", start, "err", err)
+			pageErr = err
+			break
 		}
-		log.Debugw("cmc info", "start", start, "limit", limit, "info", cmc.Status)
+		metrics.CmcPagesFetched.Inc()
+		metrics.CmcCreditsUsed.Add(float64(cmc.Status.CreditCount))
+		credits += cmc.Status.CreditCount
+		log.Debugw("cmc info", "start", start, "limit", cmcPageLimit, "info", cmc.Status)
 		for _, c := range cmc.Data {
-			tokenInfo = append(tokenInfo, common.RedisTokenInfo{
+			info := common.RedisTokenInfo{
+				ID:                    c.ID,
+				Slug:                  c.Slug,
 				Name:                  c.Name,
 				Symbol:                c.Symbol,
 				CirculatingSupply:     c.CirculatingSupply,
@@ -63,27 +155,73 @@ func (t *TokenInfoWorker) process() {
 				PercentChange1H:       c.Quote.Usd.PercentChange1H,
 				PercentChange24H:      c.Quote.Usd.PercentChange24H,
 				PercentChange7D:       c.Quote.Usd.PercentChange7D,
-			})
+			}
+			if c.Platform != nil {
+				info.Platform = cmcChain(c.Platform.Name)
+				info.TokenAddress = c.Platform.TokenAddress
+			}
+			tokenInfo = append(tokenInfo, info)
 		}
-		if len(cmc.Data) != int(limit) {
+		if len(cmc.Data) != int(cmcPageLimit) {
+			complete = true
 			break
 		}
-		start = limit + 1
+		start += cmcPageLimit
+	}
+	if len(tokenInfo) == 0 {
+		return pageErr
+	}
+	if complete {
+		t.resumeStart, t.resumeTokens = 0, nil
+	} else {
+		if t.resumeStart == 0 {
+			t.resumeAt = time.Now()
+		}
+		t.resumeStart, t.resumeTokens = start, tokenInfo
 	}
 	tokens := common.RedisTokens{
 		UpdatedTime: time.Now().Unix(),
 		Tokens:      tokenInfo,
+		Complete:    complete,
+	}
+	if !complete {
+		tokens.NextStart = start
 	}
 	data, err := json.Marshal(tokens)
 	if err != nil {
 		log.Errorw("error when marshal data", "err", err)
-		return
+		return err
 	}
 
+	if err := ctx.Err(); err != nil {
+		log.Warnw("cycle cancelled before publish", "err", err)
+		return err
+	}
 	// no expire
-	err = t.inMemDB.Set(cmcTokenInfoKey, data, 0)
+	err = t.inMemDB.Set(CmcTokenInfoKey, data, 0)
 	if err != nil {
-		log.Errorw("error when set key", "key", cmcTokenInfoKey, "err", err)
+		log.Errorw("error when set key", "key", CmcTokenInfoKey, "err", err)
+		return err
+	}
+	credits, err = t.updateMetadata(log, tokenInfo, credits)
+	if err != nil && pageErr == nil {
+		pageErr = err
+	}
+	log.Infow("finish set token info", "tokens", len(tokenInfo), "complete", complete, "credits", credits)
+	// the partial listing is published, the failed page still fails the run
+	return pageErr
+}
+
+// retry calls f until it succeeds, retrying with backoff the errors which may pass.
+// Errors other than coinmarketcap rejections are retried.
+func retry(log *zap.SugaredLogger, request string, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= cmcAttempts || !coinmarketcap.Retryable(err) {
+			return err
+		}
+		backoff := cmcRetryBackoff << (attempt - 1)
+		log.Warnw("retry request", "request", request, "attempt", attempt, "backoff", backoff, "err", err)
+		time.Sleep(backoff)
 	}
-	log.Infow("finish set token info")
 }
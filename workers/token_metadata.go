@@ -0,0 +1,209 @@
+package workers
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/kv-base-hack/base-token-rate/common"
+	"github.com/kv-base-hack/base-token-rate/lib/metrics"
+	"go.uber.org/zap"
+)
+
+// the info endpoint costs a credit per 100 tokens
+const cmcMetadataChunk = 100
+
+// the coingecko coin list is large and changes slowly
+const coingeckoListTTL = 24 * time.Hour
+
+const coingeckoTokenIndexKeyPrefix = "coingecko_token_index:"
+
+// coinmarketcap platform names which differ from the chain ids of the rate snapshot
+var cmcPlatformChains = map[string]string{
+	"bnb smart chain (bep20)": "bsc",
+	"avalanche c-chain":       "avalanche",
+	"polygon pos":             "polygon",
+}
+
+// coingecko platform ids which differ from the chain ids of the rate snapshot
+var coingeckoPlatformChains = map[string]string{
+	"binance-smart-chain": "bsc",
+	"polygon-pos":         "polygon",
+	"arbitrum-one":        "arbitrum",
+	"optimistic-ethereum": "optimism",
+}
+
+func coingeckoChain(platform string) string {
+	if chain, exist := coingeckoPlatformChains[platform]; exist {
+		return chain
+	}
+	return platform
+}
+
+func cmcChain(platform string) string {
+	platform = strings.ToLower(platform)
+	if chain, exist := cmcPlatformChains[platform]; exist {
+		return chain
+	}
+	return platform
+}
+
+// CmcTokenIndexKey is the key of the coinmarketcap info of the tokens of a chain, stored as common.RedisTokenIndex.
+func CmcTokenIndexKey(chain string) string {
+	return CmcTokenInfoKey + ":" + chain
+}
+
+// CoingeckoTokenIndexKey is the key of the coingecko coins of a chain, stored as common.RedisCoingeckoIndex.
+func CoingeckoTokenIndexKey(chain string) string {
+	return coingeckoTokenIndexKeyPrefix + chain
+}
+
+// updateMetadata gets the metadata of the listed tokens not known yet and publishes the
+// address index of every chain. It returns the credits used including the ones before.
+func (t *TokenInfoWorker) updateMetadata(log *zap.SugaredLogger, tokens []common.RedisTokenInfo, credits int) (int, error) {
+	missing := []int{}
+	for _, info := range tokens {
+		// coins without a platform are native, they have no contract address
+		if info.Platform == "" {
+			continue
+		}
+		if _, exist := t.metadata[info.ID]; !exist {
+			missing = append(missing, info.ID)
+		}
+	}
+
+	var metadataErr error
+	for bg := 0; bg < len(missing); bg += cmcMetadataChunk {
+		if t.creditBudget > 0 && credits >= t.creditBudget {
+			log.Warnw("coinmarketcap credit budget used, get metadata next run", "credits", credits, "missing", len(missing)-bg)
+			break
+		}
+		end := bg + cmcMetadataChunk
+		if end > len(missing) {
+			end = len(missing)
+		}
+		var info common.CoinMarketCapMetadataInfo
+		err := retry(log, "info", func() (err error) {
+			info, err = t.cmc.GetTokenMetadata(missing[bg:end])
+			return err
+		})
+		if err != nil {
+			log.Errorw("error when get coinmarket cap metadata", "ids", end-bg, "err", err)
+			metadataErr = err
+			break
+		}
+		metrics.CmcCreditsUsed.Add(float64(info.Status.CreditCount))
+		credits += info.Status.CreditCount
+		for _, m := range info.Data {
+			t.metadata[m.ID] = m
+		}
+	}
+
+	coingeckoCoins := t.getCoingeckoCoins(log)
+
+	index := map[string]map[string]common.RedisTokenMetadata{}
+	add := func(info common.RedisTokenInfo, chain, address string, m common.CoinMarketCapMetadata) {
+		if chain == "" || address == "" {
+			return
+		}
+		if index[chain] == nil {
+			index[chain] = map[string]common.RedisTokenMetadata{}
+		}
+		address = strings.ToLower(address)
+		info.Platform, info.TokenAddress = chain, address
+		index[chain][address] = common.RedisTokenMetadata{
+			RedisTokenInfo: info,
+			Logo:           m.Logo,
+			Urls:           m.Urls,
+			CoingeckoID:    coingeckoCoins[chain][address].ID,
+		}
+	}
+	for _, info := range tokens {
+		m := t.metadata[info.ID]
+		add(info, info.Platform, info.TokenAddress, m)
+		for _, c := range m.ContractAddress {
+			add(info, cmcChain(c.Platform.Name), c.ContractAddress, m)
+		}
+	}
+
+	now := time.Now().Unix()
+	for chain, tokens := range index {
+		data, err := json.Marshal(common.RedisTokenIndex{
+			UpdatedTime: now,
+			Chain:       chain,
+			Tokens:      tokens,
+		})
+		if err != nil {
+			log.Errorw("error when marshal data", "err", err)
+			return credits, err
+		}
+		key := CmcTokenIndexKey(chain)
+		// no expire
+		if err := t.inMemDB.Set(key, data, 0); err != nil {
+			log.Errorw("error when set key", "key", key, "err", err)
+			return credits, err
+		}
+	}
+	log.Infow("finish set token metadata", "chains", len(index), "missing", len(missing))
+	return credits, metadataErr
+}
+
+// getCoingeckoCoins gets the coingecko coins by chain and lowercase contract address, it's empty
+// without a coingecko client. The list is fetched every coingeckoListTTL and published as the
+// coingecko index of every chain, a failed fetch keeps the previous list.
+func (t *TokenInfoWorker) getCoingeckoCoins(log *zap.SugaredLogger) map[string]map[string]common.CoinGeckoCoin {
+	if t.coingecko == nil {
+		return map[string]map[string]common.CoinGeckoCoin{}
+	}
+	if t.coingeckoCoins != nil && time.Since(t.coingeckoLoadedAt) < coingeckoListTTL {
+		return t.coingeckoCoins
+	}
+	var coins []common.CoinGeckoCoin
+	err := retry(log, "coingecko_list", func() (err error) {
+		coins, err = t.coingecko.GetCoinList()
+		return err
+	})
+	if err != nil {
+		log.Errorw("error when get coingecko coin list", "err", err)
+		if t.coingeckoCoins == nil {
+			return map[string]map[string]common.CoinGeckoCoin{}
+		}
+		return t.coingeckoCoins
+	}
+	result := map[string]map[string]common.CoinGeckoCoin{}
+	for _, c := range coins {
+		for platform, address := range c.Platforms {
+			if platform == "" || address == "" {
+				continue
+			}
+			chain := coingeckoChain(platform)
+			if result[chain] == nil {
+				result[chain] = map[string]common.CoinGeckoCoin{}
+			}
+			result[chain][strings.ToLower(address)] = c
+		}
+	}
+	t.coingeckoCoins, t.coingeckoLoadedAt = result, time.Now()
+	t.publishCoingeckoIndex(log, result)
+	return result
+}
+
+func (t *TokenInfoWorker) publishCoingeckoIndex(log *zap.SugaredLogger, coins map[string]map[string]common.CoinGeckoCoin) {
+	now := time.Now().Unix()
+	for chain, tokens := range coins {
+		data, err := json.Marshal(common.RedisCoingeckoIndex{
+			UpdatedTime: now,
+			Chain:       chain,
+			Tokens:      tokens,
+		})
+		if err != nil {
+			log.Errorw("error when marshal data", "err", err)
+			return
+		}
+		key := CoingeckoTokenIndexKey(chain)
+		// no expire
+		if err := t.inMemDB.Set(key, data, 0); err != nil {
+			log.Errorw("error when set key", "key", key, "err", err)
+		}
+	}
+}